@@ -2,4 +2,4 @@ module marktome
 
 go 1.21.0
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
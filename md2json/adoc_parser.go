@@ -0,0 +1,899 @@
+package md2json
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// adocLine is line of AsciiDoc source with its place. Lines of included
+// files have no line, their nodes get no position.
+type adocLine struct {
+	text string
+	line int
+}
+
+// adocParser parses the common AsciiDoc subset line by line.
+type adocParser struct {
+	lines []adocLine
+	pos   int
+	doc   *Node
+	// markers are markers of lists the parser is inside of
+	markers []string
+	// pending are block attributes, anchor and title that go to the
+	// next block
+	pendingAttrs  string
+	pendingId     string
+	pendingTitle  string
+	footnoteCount int
+	footnotes     []Node
+}
+
+var adocHeadingRegexp = regexp.MustCompile(`^(={1,6}) +(.+?)(?: +=+)?$`)
+var adocAttributeRegexp = regexp.MustCompile(`^:([\w-]+):(?: +(.*))?$`)
+var adocAnchorRegexp = regexp.MustCompile(`^\[\[([\w:.-]+)(?:, *[^\]]*)?\]\]$`)
+var adocBlockAttrsRegexp = regexp.MustCompile(`^\[([^\[\]]*)\]$`)
+var adocTitleRegexp = regexp.MustCompile(`^\.([^\s.].*)$`)
+var adocListRegexp = regexp.MustCompile(`^\s*(\*{1,5}|-|\.{1,5}|\d+\.) +(.*)$`)
+var adocAdmonitionRegexp = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION): +(.*)$`)
+var adocIncludeRegexp = regexp.MustCompile(`^include::([^\[]+)\[[^\]]*\]$`)
+var adocBlockImageRegexp = regexp.MustCompile(`^image::([^\[]+)\[([^\]]*)\]$`)
+var adocAttrRefRegexp = regexp.MustCompile(`\{([\w-]+)\}`)
+var adocColsRegexp = regexp.MustCompile(`cols="([^"]*)"|cols=([^,]*)`)
+
+// adocAdmonitions are names of admonitions, their levels are lower case
+var adocAdmonitions = []string{"NOTE", "TIP", "IMPORTANT", "WARNING", "CAUTION"}
+
+// adocMaxIncludeDepth stops includes that include themselves
+const adocMaxIncludeDepth = 16
+
+// readAdocLines splits source to lines and puts included files in place
+// of include directives. Paths of includes are relative to dir.
+func readAdocLines(source string, path string, dir string, included bool, depth int) ([]adocLine, error) {
+	lines := []adocLine{}
+	for i, text := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		line := i + 1
+		if included {
+			line = 0
+		}
+		m := adocIncludeRegexp.FindStringSubmatch(text)
+		if m == nil {
+			lines = append(lines, adocLine{text: text, line: line})
+			continue
+		}
+		if depth >= adocMaxIncludeDepth {
+			return nil, errors.New(fmt.Sprintf("File %s:%d includes %s too deep", path, i+1, m[1]))
+		}
+		includePath := filepath.Join(dir, m[1])
+		content, err := os.ReadFile(includePath)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("File %s:%d includes missing file %s", path, i+1, m[1]))
+		}
+		inner, err := readAdocLines(string(content), includePath, filepath.Dir(includePath), true, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, inner...)
+	}
+	return lines, nil
+}
+
+// AsciidocParse parses AsciiDoc source to the document. Includes are
+// looked up relative to dir, path is used in errors.
+func AsciidocParse(source []byte, path string, dir string) (Node, error) {
+	lines, err := readAdocLines(string(source), path, dir, false, 0)
+	if err != nil {
+		return Node{}, err
+	}
+	doc := Node{
+		Type:       Document,
+		Attributes: map[string]string{},
+		Children:   []Node{},
+	}
+	p := &adocParser{lines: lines, doc: &doc}
+	doc.Children = p.blocks(func(string) bool { return false })
+	doc.Children = append(doc.Children, p.footnotes...)
+	adocXrefTitles(&doc)
+	if len(doc.Attributes) == 0 {
+		// documents without meta have no attributes, like after md2json
+		doc.Attributes = nil
+	}
+	return doc, nil
+}
+
+func Adoc2Json(input string, output string) error {
+	source, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	doc, err := AsciidocParse(source, input, filepath.Dir(input))
+	if err != nil {
+		return err
+	}
+	return WriteJson(&doc, output)
+}
+
+// Adoc2JsonDir converts AsciiDoc files of the directory to documents
+// with .md extension. Files included by others are parts of them and
+// are not converted alone.
+func Adoc2JsonDir(rootDir string, outDir string) error {
+	paths := []string{}
+	filepath.WalkDir(rootDir, func(fp string, fi os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && strings.HasSuffix(fp, ".adoc") {
+			paths = append(paths, fp)
+		}
+		return nil
+	})
+	included := map[string]bool{}
+	for _, fp := range paths {
+		source, err := os.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(source), "\n") {
+			m := adocIncludeRegexp.FindStringSubmatch(strings.TrimRight(line, "\r"))
+			if m != nil {
+				included[filepath.Join(filepath.Dir(fp), m[1])] = true
+			}
+		}
+	}
+	for _, fp := range paths {
+		if included[filepath.Clean(fp)] {
+			continue
+		}
+		rel, _ := filepath.Rel(rootDir, fp)
+		output := filepath.Join(outDir, strings.TrimSuffix(rel, ".adoc")+".md")
+		os.MkdirAll(filepath.Dir(output), os.ModePerm)
+		err := Adoc2Json(fp, output)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *adocParser) eof() bool {
+	return p.pos >= len(p.lines)
+}
+
+func (p *adocParser) current() string {
+	return p.lines[p.pos].text
+}
+
+// position spans lines from start to the current one.
+func (p *adocParser) position(start int) *Position {
+	first := p.lines[start]
+	last := p.lines[min(p.pos, len(p.lines))-1]
+	if first.line == 0 || last.line == 0 {
+		return nil
+	}
+	return &Position{
+		Start: Point{Line: first.line, Column: 1},
+		End:   Point{Line: last.line, Column: len([]rune(last.text)) + 1},
+	}
+}
+
+// takePending returns block attributes, anchor and title and forgets them.
+func (p *adocParser) takePending() (string, string, string) {
+	attrs, id, title := p.pendingAttrs, p.pendingId, p.pendingTitle
+	p.pendingAttrs, p.pendingId, p.pendingTitle = "", "", ""
+	return attrs, id, title
+}
+
+// blocks parses blocks until the line that ends enclosing block.
+func (p *adocParser) blocks(end func(string) bool) []Node {
+	nodes := []Node{}
+	for !p.eof() && !end(p.current()) {
+		nodes = append(nodes, p.block()...)
+	}
+	return nodes
+}
+
+// isAdocDelimiter tells lines that open or close delimited blocks.
+func isAdocDelimiter(line string) bool {
+	for _, d := range []string{"----", "....", "====", "____", "|===", "////", "****"} {
+		if line == d {
+			return true
+		}
+	}
+	return false
+}
+
+// block parses the block at the current line, lines of block attributes
+// produce no nodes.
+func (p *adocParser) block() []Node {
+	line := p.current()
+	trimmed := strings.TrimSpace(line)
+	start := p.pos
+	if trimmed == "" {
+		p.pos++
+		return nil
+	}
+	if line == "////" {
+		p.delimited(line)
+		p.takePending()
+		return nil
+	}
+	if strings.HasPrefix(line, "//") {
+		p.pos++
+		return nil
+	}
+	if m := adocAttributeRegexp.FindStringSubmatch(line); m != nil {
+		p.pos++
+		p.doc.Attributes[m[1]] = m[2]
+		return nil
+	}
+	if m := adocAnchorRegexp.FindStringSubmatch(line); m != nil {
+		p.pos++
+		p.pendingId = m[1]
+		return nil
+	}
+	if m := adocBlockAttrsRegexp.FindStringSubmatch(line); m != nil {
+		p.pos++
+		attrs := m[1]
+		// [#id] and [source#id,go] carry anchor of the block
+		if at := strings.Index(attrs, "#"); at >= 0 {
+			id := attrs[at+1:]
+			if comma := strings.IndexAny(id, ",.%"); comma >= 0 {
+				id = id[:comma]
+			}
+			p.pendingId = id
+			attrs = attrs[:at] + attrs[at+1+len(id):]
+		}
+		p.pendingAttrs = attrs
+		return nil
+	}
+	if m := adocTitleRegexp.FindStringSubmatch(line); m != nil && !adocListRegexp.MatchString(line) {
+		p.pos++
+		p.pendingTitle = m[1]
+		return nil
+	}
+	if m := adocHeadingRegexp.FindStringSubmatch(line); m != nil {
+		p.pos++
+		_, id, _ := p.takePending()
+		title := p.substitute(m[2])
+		if id == "" {
+			id = p.autoId(title)
+		}
+		return []Node{{
+			Type:       Heading,
+			Literal:    title,
+			Attributes: AttributeMap{"level": fmt.Sprintf("%d", len(m[1])), "id": id},
+			Position:   p.position(start),
+		}}
+	}
+	switch line {
+	case "----", "....":
+		attrs, _, title := p.takePending()
+		code := p.delimited(line)
+		n := Node{Type: CodeFence, Literal: code, Position: p.position(start)}
+		lang := adocSourceLang(attrs)
+		if lang != "" || title != "" {
+			n.Attributes = AttributeMap{}
+		}
+		if lang != "" {
+			n.Attributes["lang"] = lang
+		}
+		if title != "" {
+			n.Attributes["title"] = title
+		}
+		return []Node{n}
+	case "====", "____", "****":
+		attrs, _, title := p.takePending()
+		p.pos++
+		inner := p.blocks(func(l string) bool { return l == line })
+		p.pos++
+		if level := adocAdmonitionLevel(attrs); level != "" {
+			return []Node{p.admonition(level, title, inner, start)}
+		}
+		if line == "____" {
+			return []Node{{Type: Blockquote, Children: inner, Position: p.position(start)}}
+		}
+		// example and sidebar blocks have no kind, their content stays
+		return inner
+	case "|===":
+		return []Node{p.table(start)}
+	case "'''":
+		p.pos++
+		return []Node{{Type: ThematicBreak, Position: p.position(start)}}
+	case "<<<":
+		// page breaks are up to the writers
+		p.pos++
+		return nil
+	}
+	if m := adocBlockImageRegexp.FindStringSubmatch(line); m != nil {
+		p.pos++
+		p.takePending()
+		image := Node{Type: Image, Literal: adocAttrList(m[2])[0], Attributes: AttributeMap{"src": m[1]}}
+		return []Node{{Type: Paragraph, Children: []Node{image}, Position: p.position(start)}}
+	}
+	if adocListRegexp.MatchString(line) {
+		p.takePending()
+		return []Node{p.list()}
+	}
+	if m := adocAdmonitionRegexp.FindStringSubmatch(line); m != nil {
+		_, _, title := p.takePending()
+		p.lines[p.pos].text = m[2]
+		para := p.paragraph()
+		return []Node{p.admonition(strings.ToLower(m[1]), title, []Node{para}, start)}
+	}
+	if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+		// literal paragraph
+		p.takePending()
+		lines := []string{}
+		for !p.eof() && strings.TrimSpace(p.current()) != "" {
+			lines = append(lines, p.current())
+			p.pos++
+		}
+		return []Node{{Type: CodeFence, Literal: adocDedent(lines), Position: p.position(start)}}
+	}
+	attrs, _, title := p.takePending()
+	para := p.paragraph()
+	if level := adocAdmonitionLevel(attrs); level != "" {
+		return []Node{p.admonition(level, title, []Node{para}, start)}
+	}
+	return []Node{para}
+}
+
+// delimited returns content of delimited block which ends with the same
+// line it starts with.
+func (p *adocParser) delimited(delimiter string) string {
+	p.pos++
+	lines := []string{}
+	for !p.eof() && p.current() != delimiter {
+		lines = append(lines, p.current())
+		p.pos++
+	}
+	p.pos++
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// adocDedent removes indent common to lines of literal paragraph.
+func adocDedent(lines []string) string {
+	indent := -1
+	for _, l := range lines {
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	var text strings.Builder
+	for _, l := range lines {
+		text.WriteString(l[indent:] + "\n")
+	}
+	return text.String()
+}
+
+// adocAttrList splits attribute list of a block or macro, positional
+// attributes come first.
+func adocAttrList(attrs string) []string {
+	list := []string{}
+	for _, a := range strings.Split(attrs, ",") {
+		list = append(list, strings.Trim(strings.TrimSpace(a), `"`))
+	}
+	return list
+}
+
+// adocSourceLang returns language of [source,go] block attributes.
+func adocSourceLang(attrs string) string {
+	list := adocAttrList(attrs)
+	if len(list) > 1 && (list[0] == "source" || list[0] == "") && !strings.Contains(list[1], "=") {
+		return list[1]
+	}
+	return ""
+}
+
+// adocAdmonitionLevel returns level of [NOTE] block attributes.
+func adocAdmonitionLevel(attrs string) string {
+	style := adocAttrList(attrs)[0]
+	for _, a := range adocAdmonitions {
+		if style == a {
+			return strings.ToLower(a)
+		}
+	}
+	return ""
+}
+
+// admonition makes admonition of blocks, .Title of the block becomes
+// title of the admonition.
+func (p *adocParser) admonition(level string, title string, blocks []Node, start int) Node {
+	n := Node{Type: Admonition, Attributes: AttributeMap{"level": level}, Children: blocks, Position: p.position(start)}
+	if title != "" {
+		n.Attributes["title"] = title
+	}
+	return n
+}
+
+// isAdocBlockStart tells lines that end paragraph.
+func (p *adocParser) isAdocBlockStart(line string) bool {
+	return strings.TrimSpace(line) == "" || isAdocDelimiter(line) || line == "+" ||
+		adocListRegexp.MatchString(line) || adocBlockAttrsRegexp.MatchString(line) ||
+		adocAnchorRegexp.MatchString(line) || strings.HasPrefix(line, "//")
+}
+
+func (p *adocParser) paragraph() Node {
+	start := p.pos
+	lines := []string{strings.TrimSpace(p.current())}
+	p.pos++
+	for !p.eof() && !p.isAdocBlockStart(p.current()) && !adocHeadingRegexp.MatchString(p.current()) {
+		lines = append(lines, strings.TrimSpace(p.current()))
+		p.pos++
+	}
+	return Node{Type: Paragraph, Children: p.inlines(strings.Join(lines, "\n")), Position: p.position(start)}
+}
+
+// adocMarker normalizes marker of list item, explicit numbers are the
+// same marker as dots.
+func adocMarker(marker string) string {
+	if marker[len(marker)-1] == '.' && marker[0] != '.' {
+		return "."
+	}
+	return marker
+}
+
+func (p *adocParser) list() Node {
+	start := p.pos
+	m := adocListRegexp.FindStringSubmatch(p.current())
+	marker := adocMarker(m[1])
+	list := Node{Type: List, Children: []Node{}}
+	if marker[0] == '.' {
+		list.Attributes = AttributeMap{"ordered": "true"}
+	}
+	p.markers = append(p.markers, marker)
+	defer func() { p.markers = p.markers[:len(p.markers)-1] }()
+
+	for !p.eof() {
+		m := adocListRegexp.FindStringSubmatch(p.current())
+		if m == nil || adocMarker(m[1]) != marker {
+			break
+		}
+		itemStart := p.pos
+		item := Node{Type: ListItem}
+		text := m[2]
+		if len(text) > 4 && text[0] == '[' && text[2] == ']' && text[3] == ' ' && strings.Contains("x* ", text[1:2]) {
+			item.Attributes = AttributeMap{"checked": fmt.Sprintf("%v", text[1] != ' ')}
+			text = text[4:]
+		}
+		p.lines[p.pos].text = text
+		para := p.paragraph()
+		para.Position = p.position(itemStart)
+		item.Children = []Node{para}
+		for !p.eof() {
+			if p.current() == "+" {
+				// continuation attaches the next block to the item
+				p.pos++
+				for !p.eof() {
+					blocks := p.block()
+					if len(blocks) > 0 {
+						item.Children = append(item.Children, blocks...)
+						break
+					}
+				}
+				continue
+			}
+			next := p.nextNonBlank()
+			if next < len(p.lines) {
+				m := adocListRegexp.FindStringSubmatch(p.lines[next].text)
+				if m != nil && !p.inList(adocMarker(m[1])) {
+					p.pos = next
+					item.Children = append(item.Children, p.list())
+					continue
+				}
+			}
+			break
+		}
+		item.Position = p.position(itemStart)
+		list.Children = append(list.Children, item)
+		next := p.nextNonBlank()
+		if next < len(p.lines) {
+			m := adocListRegexp.FindStringSubmatch(p.lines[next].text)
+			if m != nil && adocMarker(m[1]) == marker {
+				p.pos = next
+			}
+		}
+	}
+	list.Position = p.position(start)
+	return list
+}
+
+func (p *adocParser) nextNonBlank() int {
+	next := p.pos
+	for next < len(p.lines) && strings.TrimSpace(p.lines[next].text) == "" {
+		next++
+	}
+	return next
+}
+
+func (p *adocParser) inList(marker string) bool {
+	for _, m := range p.markers {
+		if m == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// adocColumnAligns reads alignment of columns from cols attribute like
+// "<1,^2,3*>", a single number like "3" is the number of columns.
+func adocColumnAligns(cols string) []string {
+	aligns := []string{}
+	if cols == "" {
+		return aligns
+	}
+	if count, err := strconv.Atoi(strings.TrimSpace(cols)); err == nil {
+		return make([]string, max(count, 0))
+	}
+	for _, spec := range strings.Split(cols, ",") {
+		spec = strings.TrimSpace(spec)
+		repeat := 1
+		if at := strings.Index(spec, "*"); at > 0 {
+			fmt.Sscan(spec[:at], &repeat)
+			spec = spec[at+1:]
+		}
+		align := ""
+		switch {
+		case strings.Contains(spec, "<"):
+			align = "left"
+		case strings.Contains(spec, "^"):
+			align = "center"
+		case strings.Contains(spec, ">"):
+			align = "right"
+		}
+		for i := 0; i < repeat; i++ {
+			aligns = append(aligns, align)
+		}
+	}
+	return aligns
+}
+
+// adocCellSpec is the cell specifier before the pipe like "2+", "3*",
+// "^.>" or "a": duplication, span, alignment and style.
+const adocCellSpec = `(\d+\*)?(?:(\d+)?(?:\.\d+)?\+)?[<^>]?(?:\.[<^>])?[adehlmsv]?`
+
+var adocCellStartRegexp = regexp.MustCompile(`^` + adocCellSpec + `$`)
+var adocCellEndRegexp = regexp.MustCompile(`\s(` + adocCellSpec + `)$`)
+
+// adocCell is cell of table, span is the number of columns it takes.
+type adocCell struct {
+	text string
+	span int
+}
+
+// splitAdocCells splits line of table to cells, \| is a pipe inside cell.
+// Cells start with a pipe, optionally after the cell specifier, so false
+// means the line continues the last cell.
+func splitAdocCells(line string) ([]adocCell, bool) {
+	cells := []adocCell{}
+	line = strings.ReplaceAll(line, `\|`, "\x00")
+	parts := strings.Split(line, "|")
+	if len(parts) < 2 || !adocCellStartRegexp.MatchString(parts[0]) {
+		return cells, false
+	}
+	spec := parts[0]
+	for i, c := range parts[1:] {
+		next := ""
+		if i < len(parts)-2 {
+			// the specifier of the next cell ends this one
+			if m := adocCellEndRegexp.FindStringSubmatchIndex(c); m != nil {
+				next = c[m[2]:m[3]]
+				c = c[:m[0]]
+			}
+		}
+		m := adocCellStartRegexp.FindStringSubmatch(spec)
+		repeat, span := 1, 1
+		if m[1] != "" {
+			fmt.Sscan(strings.TrimSuffix(m[1], "*"), &repeat)
+		}
+		if m[2] != "" {
+			fmt.Sscan(m[2], &span)
+		}
+		text := strings.TrimSpace(strings.ReplaceAll(c, "\x00", "|"))
+		for j := 0; j < repeat; j++ {
+			cells = append(cells, adocCell{text: text, span: max(span, 1)})
+		}
+		spec = next
+	}
+	return cells, true
+}
+
+// table parses |=== table. Markdown tables always have header, so the
+// first row is the header even when AsciiDoc doesn't mark it.
+func (p *adocParser) table(start int) Node {
+	attrs, _, _ := p.takePending()
+	cols := ""
+	if m := adocColsRegexp.FindStringSubmatch(attrs); m != nil {
+		cols = m[1] + m[2]
+	}
+	aligns := adocColumnAligns(cols)
+
+	p.pos++
+	cells := []adocCell{}
+	columns := len(aligns)
+	for !p.eof() && p.current() != "|===" {
+		line := strings.TrimSpace(p.current())
+		p.pos++
+		if line == "" {
+			continue
+		}
+		lineCells, ok := splitAdocCells(line)
+		if !ok {
+			// line without cell continues the last cell, Markdown cell
+			// is one line
+			if len(cells) > 0 {
+				cells[len(cells)-1].text += " " + line
+			}
+			continue
+		}
+		if columns == 0 {
+			for _, c := range lineCells {
+				columns += c.span
+			}
+		}
+		cells = append(cells, lineCells...)
+	}
+	p.pos++
+	// Markdown cells don't span, empty cells take the rest of the span
+	texts := []string{}
+	for _, c := range cells {
+		texts = append(texts, c.text)
+		for i := 1; i < c.span; i++ {
+			texts = append(texts, "")
+		}
+	}
+
+	table := Node{Type: Table, Position: p.position(start)}
+	head := Node{Type: TableHead}
+	body := Node{Type: TableBody}
+	for i, c := range texts {
+		cell := Node{Type: TableCell, Children: p.inlines(c)}
+		col := i % max(columns, 1)
+		if i < columns {
+			if col < len(aligns) && aligns[col] != "" {
+				cell.Attributes = AttributeMap{"align": aligns[col]}
+			}
+			head.Children = append(head.Children, cell)
+			continue
+		}
+		if col == 0 {
+			body.Children = append(body.Children, Node{Type: TableRow})
+		}
+		row := &body.Children[len(body.Children)-1]
+		row.Children = append(row.Children, cell)
+	}
+	table.Children = []Node{head, body}
+	return table
+}
+
+// autoId makes id of section like Asciidoctor: _section_title, prefix and
+// separator come from idprefix and idseparator attributes.
+func (p *adocParser) autoId(title string) string {
+	prefix, ok := p.doc.Attributes["idprefix"]
+	if !ok {
+		prefix = "_"
+	}
+	separator, ok := p.doc.Attributes["idseparator"]
+	if !ok {
+		separator = "_"
+	}
+	var id strings.Builder
+	pending := false
+	for _, c := range strings.ToLower(title) {
+		if c == '-' || c == '.' || c == '_' || isAdocWordRune(c) {
+			if pending && id.Len() > 0 {
+				id.WriteString(separator)
+			}
+			pending = false
+			id.WriteRune(c)
+		} else {
+			pending = true
+		}
+	}
+	return prefix + id.String()
+}
+
+func isAdocWordRune(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c > 127
+}
+
+// substitute replaces references to document attributes like {version}.
+func (p *adocParser) substitute(text string) string {
+	return adocAttrRefRegexp.ReplaceAllStringFunc(text, func(ref string) string {
+		v, ok := p.doc.Attributes[ref[1:len(ref)-1]]
+		if !ok {
+			return ref
+		}
+		return v
+	})
+}
+
+// adocXrefTitles gives text to xrefs without it: title of the section
+// they lead to or its id.
+func adocXrefTitles(doc *Node) {
+	titles := map[string]string{}
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		if n.Type == Heading {
+			titles[n.Attributes["id"]] = n.Literal
+		}
+		for i := range n.Children {
+			collect(&n.Children[i])
+		}
+	}
+	collect(doc)
+	var fill func(n *Node)
+	fill = func(n *Node) {
+		if n.Type == Link && n.Literal == "" {
+			anchor := n.Attributes["anchor"]
+			title, ok := titles[anchor]
+			if !ok {
+				title = anchor
+			}
+			n.Literal = title
+		}
+		for i := range n.Children {
+			fill(&n.Children[i])
+		}
+	}
+	fill(doc)
+}
+
+var adocXrefRegexp = regexp.MustCompile(`^<<([\w:.-]+)(?:, *([^>]*))?>>`)
+var adocXrefMacroRegexp = regexp.MustCompile(`^xref:([^\[\s]+)\[([^\]]*)\]`)
+var adocLinkMacroRegexp = regexp.MustCompile(`^(?:link:([^\[\s]+)|((?:https?|ftp)://[^\[\s]+|mailto:[^\[\s]+))\[([^\]]*)\]`)
+var adocUrlRegexp = regexp.MustCompile(`^(?:https?|ftp)://[^\s\[<]+`)
+var adocImageRegexp = regexp.MustCompile(`^image:([^\[\s:][^\[\s]*)\[([^\]]*)\]`)
+var adocFootnoteRegexp = regexp.MustCompile(`^footnote:\[([^\]]*)\]`)
+var adocInlineAnchorRegexp = regexp.MustCompile(`^\[\[[\w:.-]+\]\]`)
+
+// isAdocBoundary tells characters around constrained formatting marks.
+func isAdocBoundary(c byte) bool {
+	return strings.IndexByte(" \t\n([{\"'.,;:!?)]}-", c) >= 0
+}
+
+// adocQuoted finds formatted text at the start of text, like *bold* or
+// **bold**, and returns its content and size.
+func adocQuoted(text string, prev byte, mark byte) (string, int, bool) {
+	double := string([]byte{mark, mark})
+	if strings.HasPrefix(text, double) {
+		end := strings.Index(text[2:], double)
+		if end > 0 {
+			return text[2 : 2+end], end + 4, true
+		}
+	}
+	if text[0] != mark || !isAdocBoundary(prev) || len(text) < 3 || text[1] == ' ' || text[1] == mark {
+		return "", 0, false
+	}
+	for i := 2; i < len(text); i++ {
+		if text[i] == mark && text[i-1] != ' ' && (i+1 == len(text) || isAdocBoundary(text[i+1])) {
+			return text[1:i], i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// inlines parses inline markup of the text, references to document
+// attributes are substituted first.
+func (p *adocParser) inlines(text string) []Node {
+	text = p.substitute(text)
+	nodes := []Node{}
+	var plain strings.Builder
+	flush := func() {
+		if plain.Len() > 0 {
+			nodes = append(nodes, Node{Type: Text, Literal: plain.String()})
+			plain.Reset()
+		}
+	}
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		prev := byte(' ')
+		if i > 0 {
+			prev = text[i-1]
+		}
+		node, size := p.inline(rest, prev)
+		if size == 0 {
+			plain.WriteByte(text[i])
+			i++
+			continue
+		}
+		flush()
+		if node != nil {
+			nodes = append(nodes, *node)
+		}
+		i += size
+	}
+	flush()
+	return nodes
+}
+
+// inline parses markup at the start of text. Zero size means plain
+// text, nil node means markup that produces nothing.
+func (p *adocParser) inline(text string, prev byte) (*Node, int) {
+	if strings.HasPrefix(text, " +\n") || text == " +" {
+		// hard line break swallows the newline, writers put their own
+		return &Node{Type: LineBreak}, min(len(text), 3)
+	}
+	if m := adocXrefRegexp.FindStringSubmatch(text); m != nil {
+		return &Node{Type: Link, Literal: m[2], Attributes: AttributeMap{"anchor": m[1]}}, len(m[0])
+	}
+	if m := adocXrefMacroRegexp.FindStringSubmatch(text); m != nil {
+		return adocXref(m[1], m[2]), len(m[0])
+	}
+	if m := adocLinkMacroRegexp.FindStringSubmatch(text); m != nil {
+		url := m[1] + m[2]
+		label := adocAttrList(m[3])[0]
+		if label == "" {
+			label = url
+		}
+		n := Node{Type: Link, Literal: label, Attributes: AttributeMap{}}
+		setLinkUrl(&n, url)
+		return &n, len(m[0])
+	}
+	if m := adocUrlRegexp.FindString(text); m != "" && isAdocBoundary(prev) {
+		url := strings.TrimRight(m, ".,:;!?)")
+		return &Node{Type: Link, Literal: url, Attributes: AttributeMap{"href": url, "autolink": "bare"}}, len(url)
+	}
+	if m := adocImageRegexp.FindStringSubmatch(text); m != nil {
+		return &Node{Type: Image, Literal: adocAttrList(m[2])[0], Attributes: AttributeMap{"src": m[1]}}, len(m[0])
+	}
+	if m := adocFootnoteRegexp.FindStringSubmatch(text); m != nil {
+		p.footnoteCount++
+		id := fmt.Sprintf("%d", p.footnoteCount)
+		def := Node{Type: FootnoteDef, Attributes: AttributeMap{"id": id},
+			Children: []Node{{Type: Paragraph, Children: p.inlines(m[1])}}}
+		p.footnotes = append(p.footnotes, def)
+		return &Node{Type: FootnoteRef, Attributes: AttributeMap{"id": id}}, len(m[0])
+	}
+	if m := adocInlineAnchorRegexp.FindString(text); m != "" {
+		return nil, len(m)
+	}
+	if text[0] == '`' {
+		end := strings.IndexByte(text[1:], '`')
+		if end > 0 {
+			return &Node{Type: Code, Literal: text[1 : 1+end]}, end + 2
+		}
+	}
+	kinds := map[byte]Kind{'*': Bold, '_': Emphasis}
+	if kind, ok := kinds[text[0]]; ok {
+		if inner, size, ok := adocQuoted(text, prev, text[0]); ok {
+			n := Node{Type: kind}
+			children := p.inlines(inner)
+			if len(children) == 1 && children[0].Type == Text {
+				n.Literal = children[0].Literal
+			} else {
+				n.Children = children
+			}
+			return &n, size
+		}
+	}
+	return nil, 0
+}
+
+// adocXref makes link of xref:target[text]. Ids are unique in the whole
+// project, so links to sections are just anchors superlinks resolve,
+// links to whole documents lead to their markdown.
+func adocXref(target string, label string) *Node {
+	n := Node{Type: Link, Literal: label, Attributes: AttributeMap{}}
+	file, anchor, hasAnchor := strings.Cut(target, "#")
+	switch {
+	case hasAnchor && anchor != "":
+		n.Attributes["anchor"] = anchor
+	case strings.HasSuffix(file, ".adoc"):
+		n.Attributes["href"] = strings.TrimSuffix(file, ".adoc") + ".md"
+		if label == "" {
+			n.Literal = strings.TrimSuffix(filepath.Base(file), ".adoc")
+		}
+	default:
+		n.Attributes["anchor"] = file
+	}
+	return &n
+}
@@ -0,0 +1,69 @@
+package md2json_test
+
+import (
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAsciidocParse(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/adoc/*.adoc")
+	for _, fp := range paths {
+		name := strings.TrimSuffix(fp, ".adoc")
+		t.Run(name, func(t *testing.T) {
+			input, _ := os.ReadFile(fp)
+			expected, _ := os.ReadFile(name + ".md")
+			doc, err := md2json.AsciidocParse(input, fp, filepath.Dir(fp))
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := md2json.WriteDocument(&doc)
+			if string(text) != string(expected) {
+				t.Errorf("AsciidocParse()\nactual\n%s\nexpected\n%s", text, expected)
+			}
+		})
+	}
+}
+
+func TestAsciidocPositions(t *testing.T) {
+	source := "= Title\n\nFirst paragraph\ncontinues.\n\n----\ncode\n----\n"
+	doc, err := md2json.AsciidocParse([]byte(source), "page.adoc", ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"page.adoc:1:1", "page.adoc:3:1", "page.adoc:6:1"}
+	for i, n := range doc.Children {
+		if location := n.Location("page.adoc"); location != expected[i] {
+			t.Errorf("%s at %s, expected %s", n.Type, location, expected[i])
+		}
+	}
+}
+
+func TestAsciidocMissingInclude(t *testing.T) {
+	source := "= Title\n\ninclude::missing.adoc[]\n"
+	_, err := md2json.AsciidocParse([]byte(source), "page.adoc", t.TempDir())
+	expected := "File page.adoc:3 includes missing file missing.adoc"
+	if err == nil || err.Error() != expected {
+		t.Errorf("AsciidocParse() error %v, expected %s", err, expected)
+	}
+}
+
+func TestAdoc2JsonDir(t *testing.T) {
+	out := t.TempDir()
+	err := md2json.Adoc2JsonDir("testdata/adoc", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted := md2json.ListAllMd(out)
+	expected := []string{filepath.Join(out, "manual.md"), filepath.Join(out, "tables.md")}
+	if strings.Join(converted, ",") != strings.Join(expected, ",") {
+		t.Errorf("Adoc2JsonDir() converted %v, expected %v", converted, expected)
+	}
+	doc, _ := md2json.ReadJson(expected[0])
+	title, id, _ := doc.Heading()
+	if title != "Administrator Manual" || id != "_administrator_manual" {
+		t.Errorf("Heading() %s {#%s}", title, id)
+	}
+}
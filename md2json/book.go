@@ -0,0 +1,150 @@
+package md2json
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// bookEntry is a page or a section of mkdocs nav with its heading level
+// in the book: -1 is part, 0 is chapter, 1 is section and so on.
+type bookEntry struct {
+	title string
+	page  string
+	level int
+}
+
+// bookNav flattens nav into entries. Top level sections become parts if
+// there are any, nested sections and pages go one level deeper. Pages
+// after a part would fall into it, so with such pages sections stay
+// chapters.
+func bookNav(items []navItem) []bookEntry {
+	base := 0
+	for _, item := range items {
+		if item.page == "" {
+			base = -1
+		} else if base < 0 {
+			base = 0
+			break
+		}
+	}
+	entries := []bookEntry{}
+	var walk func(items []navItem, level int)
+	walk = func(items []navItem, level int) {
+		pageLevel := level
+		if pageLevel < 0 {
+			pageLevel = 0
+		}
+		for _, item := range items {
+			if item.page != "" {
+				entries = append(entries, bookEntry{title: item.title, page: item.page, level: pageLevel})
+				continue
+			}
+			entries = append(entries, bookEntry{title: item.title, level: level})
+			walk(item.children, level+1)
+		}
+	}
+	walk(items, base)
+	return entries
+}
+
+// shiftHeadings moves headings of the page so its level 1 heading
+// gets the level of the page in the book.
+func shiftHeadings(n *Node, shift int) {
+	if n.Type == Heading {
+		attrs := AttributeMap{}
+		for k, v := range n.Attributes {
+			attrs[k] = v
+		}
+		level, err := strconv.Atoi(attrs["level"])
+		if err != nil {
+			level = 3
+		}
+		attrs["level"] = fmt.Sprintf("%d", level+shift)
+		n.Attributes = attrs
+	}
+	for i := range n.Children {
+		shiftHeadings(&n.Children[i], shift)
+	}
+}
+
+// linkPages points links to whole pages of the book at their headings,
+// so they become \hyperref like links to anchors.
+func linkPages(n *Node, pageIds map[string]string) {
+	if n.Type == Link {
+		href, _ := n.Attributes["href"]
+		anchor, _ := n.Attributes["anchor"]
+		id, ok := pageIds[filepath.Clean(href)]
+		if ok && anchor == "" {
+			attrs := AttributeMap{}
+			for k, v := range n.Attributes {
+				attrs[k] = v
+			}
+			attrs["anchor"] = id
+			n.Attributes = attrs
+		}
+	}
+	for i := range n.Children {
+		linkPages(&n.Children[i], pageIds)
+	}
+}
+
+// LatexBook writes all pages of the nav of planarized mkdocs config as
+// one book with title page and table of contents.
+func LatexBook(mkdocsPath string, opts TexOptions) ([]byte, error) {
+	mkdocs, inDir, items, err := readMkdocsNav(mkdocsPath)
+	if err != nil {
+		return nil, err
+	}
+	entries := bookNav(items)
+
+	pages := map[string]Node{}
+	pageIds := map[string]string{}
+	for _, e := range entries {
+		if e.page == "" {
+			continue
+		}
+		doc, err := ReadJson(filepath.Join(inDir, e.page))
+		if err != nil {
+			return nil, err
+		}
+		pages[e.page] = doc
+		_, id, found := doc.Heading()
+		if found && id != "" {
+			pageIds[filepath.Clean(e.page)] = id
+		}
+	}
+
+	var text bytes.Buffer
+	text.WriteString("\\frontmatter\n")
+	title, ok := mkdocs["site_name"]
+	if ok {
+		text.WriteString(fmt.Sprintf("\\title{%s}\n", escapeTexText(fmt.Sprintf("%v", title))))
+		author, ok := mkdocs["site_author"]
+		if ok {
+			text.WriteString(fmt.Sprintf("\\author{%s}\n", escapeTexText(fmt.Sprintf("%v", author))))
+		}
+		text.WriteString("\\maketitle\n")
+	}
+	text.WriteString("\\tableofcontents\n")
+	text.WriteString("\\mainmatter\n\n")
+	for _, e := range entries {
+		if e.page == "" {
+			section := Node{Type: Heading, Literal: e.title, Attributes: AttributeMap{"level": fmt.Sprintf("%d", e.level)}}
+			text.Write(writeTexHeading(&section))
+			continue
+		}
+		doc := pages[e.page]
+		shiftHeadings(&doc, e.level-1)
+		linkPages(&doc, pageIds)
+		pageOpts := opts
+		pageOpts.Path = filepath.Join(inDir, e.page)
+		tex, err := LatexOptions(&doc, pageOpts)
+		if err != nil {
+			return nil, err
+		}
+		text.Write(tex)
+	}
+	return text.Bytes(), nil
+}
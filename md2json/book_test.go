@@ -0,0 +1,33 @@
+package md2json_test
+
+import (
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLatexBook(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), os.ModePerm)
+	for _, fp := range md2json.ListAllMd("testdata/book/docs") {
+		err := md2json.Md2Json(fp, filepath.Join(dir, "docs", filepath.Base(fp)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// pages after sections keep them chapters, otherwise they are parts
+	for config, book := range map[string]string{"mkdocs.yml": "book.tex", "parts.yml": "parts.tex"} {
+		mkdocs, _ := os.ReadFile(filepath.Join("testdata/book", config))
+		os.WriteFile(filepath.Join(dir, config), mkdocs, os.ModePerm)
+
+		tex, err := md2json.LatexBook(filepath.Join(dir, config), md2json.TexOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := os.ReadFile(filepath.Join("testdata/book", book))
+		if string(tex) != string(expected) {
+			t.Errorf("LatexBook(%s)\nactual\n%s\nexpected\n%s", config, tex, expected)
+		}
+	}
+}
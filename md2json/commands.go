@@ -12,18 +12,32 @@ import (
 type CommandFunction func([]string) error
 
 var Commands = map[string]CommandFunction{
-	"md2json":     Commmand_md2json,
-	"planarize":   Command_planarize,
-	"superlinks":  Command_superlinks,
-	"snippets":    Command_snippets,
-	"graphviz":    Command_graphviz,
-	"macros":      Command_macros,
-	"json2md":     Command_json2md,
-	"lint":        Command_lint,
-	"json2latex":  Command_json2latex,
-	"heading":     Command_heading,
-	"copy-images": Command_copyImages,
-	"mkdocs":      Command_mkdocs,
+	"md2json":         Commmand_md2json,
+	"adoc2json":       Command_adoc2json,
+	"html2json":       Command_html2json,
+	"planarize":       Command_planarize,
+	"superlinks":      Command_superlinks,
+	"footnotes":       Command_footnotes,
+	"snippets":        Command_snippets,
+	"graphviz":        Command_graphviz,
+	"svg2pdf":         Command_svg2pdf,
+	"macros":          Command_macros,
+	"json2md":         Command_json2md,
+	"lint":            Command_lint,
+	"json2latex":      Command_json2latex,
+	"json2book":       Command_json2book,
+	"json2html":       Command_json2html,
+	"site":            Command_site,
+	"epub":            Command_epub,
+	"json2docx":       Command_json2docx,
+	"json2pandoc":     Command_json2pandoc,
+	"json2man":        Command_json2man,
+	"json2text":       Command_json2text,
+	"json2confluence": Command_json2confluence,
+	"pandoc2json":     Command_pandoc2json,
+	"heading":         Command_heading,
+	"copy-images":     Command_copyImages,
+	"mkdocs":          Command_mkdocs,
 }
 
 func Command_mkdocs(args []string) error {
@@ -82,6 +96,13 @@ func Command_superlinks(args []string) error {
 	return CrosscheckSuperlinks(args[0])
 }
 
+func Command_footnotes(args []string) error {
+	if len(args) < 1 {
+		return errors.New(fmt.Sprintf("usage: footnotes dir"))
+	}
+	return Footnotes(args[0])
+}
+
 func Command_snippets(args []string) error {
 	if len(args) < 1 {
 		return errors.New(fmt.Sprintf("usage: snippets dir"))
@@ -89,12 +110,46 @@ func Command_snippets(args []string) error {
 	return CopySnippets(args[0])
 }
 
+func Command_adoc2json(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: adoc2json input_dir|input.adoc output_dir|output.md"))
+	}
+	st, err := os.Stat(args[0])
+	if err != nil {
+		return err
+	}
+	if st.IsDir() {
+		return Adoc2JsonDir(args[0], args[1])
+	}
+	os.MkdirAll(filepath.Dir(args[1]), os.ModePerm)
+	return Adoc2Json(args[0], args[1])
+}
+
+func Command_html2json(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: html2json input_dir|input.html output_dir|output.md"))
+	}
+	st, err := os.Stat(args[0])
+	if err != nil {
+		return err
+	}
+	if st.IsDir() {
+		return Html2JsonDir(args[0], args[1])
+	}
+	os.MkdirAll(filepath.Dir(args[1]), os.ModePerm)
+	return Html2Json(args[0], args[1])
+}
+
 func Command_json2md(args []string) error {
 	if len(args) < 2 {
-		return errors.New(fmt.Sprintf("usage: json2md input_dir output_dir"))
+		return errors.New(fmt.Sprintf("usage: json2md input_dir output_dir [reflinks]"))
 	}
 	inDir := args[0]
 	outDir := args[1]
+	opts, err := parseWriteOptions(args[2:])
+	if err != nil {
+		return err
+	}
 
 	st, err := os.Stat(inDir)
 	if err != nil {
@@ -102,14 +157,14 @@ func Command_json2md(args []string) error {
 	}
 	if !st.IsDir() {
 		os.MkdirAll(filepath.Dir(outDir), os.ModePerm)
-		err := Json2Md(inDir, outDir)
+		err := Json2Md(inDir, outDir, opts)
 		return err
 	}
 
 	for _, out := range ListAllMd(inDir) {
 		out2 := outDir + "/" + strings.TrimPrefix(out, inDir+"/")
 		os.MkdirAll(filepath.Dir(out2), os.ModePerm)
-		err := Json2Md(out, out2)
+		err := Json2Md(out, out2, opts)
 		if err != nil {
 			return err
 		}
@@ -117,6 +172,20 @@ func Command_json2md(args []string) error {
 	return nil
 }
 
+// parseWriteOptions reads trailing markdown writer args of json2md and lint
+func parseWriteOptions(args []string) (WriteOptions, error) {
+	opts := WriteOptions{}
+	for len(args) > 0 {
+		if args[0] == "reflinks" {
+			opts.ReferenceLinks = true
+			args = args[1:]
+			continue
+		}
+		return opts, errors.New(fmt.Sprintf("Unknown markdown writer args %v", args))
+	}
+	return opts, nil
+}
+
 func Command_macros(args []string) error {
 	if len(args) < 3 {
 		return errors.New(fmt.Sprintf("usage: macros foliant.yml srcDir destDir"))
@@ -131,9 +200,27 @@ func Command_graphviz(args []string) error {
 	return Graphviz(args[0], args[1], args[2])
 }
 
+func Command_svg2pdf(args []string) error {
+	if len(args) < 3 {
+		return errors.New(fmt.Sprintf("usage: svg2pdf srcDir imageDir cacheDir [converter \"command {in} {out}\"]"))
+	}
+	converter := DefaultSvgConverter
+	if len(args) > 3 {
+		if args[3] != "converter" || len(args) != 5 {
+			return errors.New(fmt.Sprintf("Unknown svg2pdf args %v", args[3:]))
+		}
+		converter = args[4]
+	}
+	return Svg2Pdf(args[0], args[1], args[2], converter)
+}
+
 func Command_lint(args []string) error {
 	if len(args) < 1 {
-		return errors.New(fmt.Sprintf("usage: lint file.md"))
+		return errors.New(fmt.Sprintf("usage: lint file.md [reflinks]"))
+	}
+	opts, err := parseWriteOptions(args[1:])
+	if err != nil {
+		return err
 	}
 	f, err := os.CreateTemp("/tmp", "md2json-")
 	if err != nil {
@@ -144,19 +231,28 @@ func Command_lint(args []string) error {
 	if err != nil {
 		return err
 	}
-	err = Json2Md(f.Name(), args[0])
+	err = Json2Md(f.Name(), args[0], opts)
 	return err
 }
 
 func Command_json2latex(args []string) error {
 	if len(args) < 2 {
-		return errors.New(fmt.Sprintf("usage: json2latex input_dir output.tex"))
+		return errors.New(fmt.Sprintf("usage: json2latex input_dir output.tex [addheading level] [highlight listings|minted] [languages languages.yml] [svg skip|svg]"))
 	}
 	input := args[0]
 	output := args[1]
 	args = args[2:]
 	level := -1
+	opts := TexOptions{Path: input}
 	for len(args) > 0 {
+		n, err := parseTexOption(args, &opts)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			args = args[n:]
+			continue
+		}
 		if args[0] == "addheading" {
 			if len(args) < 2 {
 				return errors.New(fmt.Sprintf("addheading level"))
@@ -185,14 +281,223 @@ func Command_json2latex(args []string) error {
 			}
 		}
 	}
-	tex, err := Latex(&doc)
+	tex, err := LatexOptions(&doc, opts)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(output, tex, os.ModePerm)
+	return err
+}
+
+// parseTexOption reads one LaTeX writer option from args and returns
+// the number of args it took, zero when args start with something else.
+func parseTexOption(args []string, opts *TexOptions) (int, error) {
+	if args[0] == "highlight" {
+		if len(args) < 2 {
+			return 0, errors.New(fmt.Sprintf("highlight listings|minted"))
+		}
+		if args[1] != "listings" && args[1] != "minted" {
+			return 0, errors.New(fmt.Sprintf("Unknown highlight package %s", args[1]))
+		}
+		opts.Highlight = args[1]
+		return 2, nil
+	}
+	if args[0] == "languages" {
+		if len(args) < 2 {
+			return 0, errors.New(fmt.Sprintf("languages languages.yml"))
+		}
+		languages, err := YamlParse(args[1])
+		if err != nil {
+			return 0, err
+		}
+		opts.Languages = map[string]string{}
+		for k, v := range languages {
+			opts.Languages[k] = fmt.Sprintf("%v", v)
+		}
+		return 2, nil
+	}
+	if args[0] == "svg" {
+		if len(args) < 2 {
+			return 0, errors.New(fmt.Sprintf("svg skip|svg"))
+		}
+		if args[1] != "skip" && args[1] != "svg" {
+			return 0, errors.New(fmt.Sprintf("Unknown svg mode %s", args[1]))
+		}
+		opts.Svg = args[1]
+		return 2, nil
+	}
+	return 0, nil
+}
+
+func Command_json2book(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: json2book mkdocs.yml output.tex [highlight listings|minted] [languages languages.yml] [svg skip|svg]"))
+	}
+	input := args[0]
+	output := args[1]
+	args = args[2:]
+	opts := TexOptions{}
+	for len(args) > 0 {
+		n, err := parseTexOption(args, &opts)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New(fmt.Sprintf("Unknown json2book args %v", args))
+		}
+		args = args[n:]
+	}
+	tex, err := LatexBook(input, opts)
 	if err != nil {
 		return err
 	}
+	os.MkdirAll(filepath.Dir(output), os.ModePerm)
 	err = os.WriteFile(output, tex, os.ModePerm)
 	return err
 }
 
+func Command_json2html(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: json2html input_dir output_dir [template page.html]"))
+	}
+	inDir := args[0]
+	outDir := args[1]
+	args = args[2:]
+	opts := HtmlPageOptions{}
+	for len(args) > 0 {
+		if args[0] == "template" {
+			if len(args) < 2 {
+				return errors.New(fmt.Sprintf("template page.html"))
+			}
+			tmpl, err := ReadHtmlTemplate(args[1])
+			if err != nil {
+				return err
+			}
+			opts.Template = tmpl
+			args = args[2:]
+			continue
+		}
+		return errors.New(fmt.Sprintf("Unknown json2html args %v", args))
+	}
+
+	st, err := os.Stat(inDir)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		os.MkdirAll(filepath.Dir(outDir), os.ModePerm)
+		return Json2Html(inDir, outDir, opts)
+	}
+	for _, fp := range ListAllMd(inDir) {
+		output := htmlOutput(outDir, strings.TrimPrefix(fp, inDir+"/"))
+		os.MkdirAll(filepath.Dir(output), os.ModePerm)
+		err := Json2Html(fp, output, opts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Command_site(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: site mkdocs.yml output_dir [template page.html]"))
+	}
+	opts := SiteOptions{}
+	if len(args) > 2 {
+		if args[2] != "template" || len(args) != 4 {
+			return errors.New(fmt.Sprintf("Unknown site args %v", args[2:]))
+		}
+		tmpl, err := ReadHtmlTemplate(args[3])
+		if err != nil {
+			return err
+		}
+		opts.Template = tmpl
+	}
+	return BuildSite(args[0], args[1], opts)
+}
+
+func Command_epub(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: epub mkdocs.yml output.epub"))
+	}
+	return Epub(args[0], args[1])
+}
+
+func Command_json2docx(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: json2docx input.md output.docx [images dir]"))
+	}
+	imageDir := filepath.Dir(args[0])
+	if len(args) > 2 {
+		if args[2] != "images" || len(args) != 4 {
+			return errors.New(fmt.Sprintf("Unknown json2docx args %v", args[2:]))
+		}
+		imageDir = args[3]
+	}
+	os.MkdirAll(filepath.Dir(args[1]), os.ModePerm)
+	return Json2Docx(args[0], args[1], imageDir)
+}
+
+func Command_json2pandoc(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: json2pandoc input.md output.json"))
+	}
+	os.MkdirAll(filepath.Dir(args[1]), os.ModePerm)
+	return Json2Pandoc(args[0], args[1])
+}
+
+func Command_pandoc2json(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: pandoc2json input.json output.md"))
+	}
+	os.MkdirAll(filepath.Dir(args[1]), os.ModePerm)
+	return Pandoc2Json(args[0], args[1])
+}
+
+func Command_json2man(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: json2man input.md output.1"))
+	}
+	return Json2Man(args[0], args[1])
+}
+
+func Command_json2text(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: json2text input.md output.txt [width N]"))
+	}
+	opts := TextOptions{}
+	if len(args) > 2 {
+		if args[2] != "width" || len(args) != 4 {
+			return errors.New(fmt.Sprintf("Unknown json2text args %v", args[2:]))
+		}
+		width, err := strconv.Atoi(args[3])
+		if err != nil {
+			return err
+		}
+		if width < MinTextWidth {
+			return errors.New(fmt.Sprintf("json2text width %d is less than %d", width, MinTextWidth))
+		}
+		opts.Width = width
+	}
+	os.MkdirAll(filepath.Dir(args[1]), os.ModePerm)
+	return Json2Text(args[0], args[1], opts)
+}
+
+func Command_json2confluence(args []string) error {
+	if len(args) < 2 {
+		return errors.New(fmt.Sprintf("usage: json2confluence inputDir outputDir [images dir]"))
+	}
+	imageDir := args[0]
+	if len(args) > 2 {
+		if args[2] != "images" || len(args) != 4 {
+			return errors.New(fmt.Sprintf("Unknown json2confluence args %v", args[2:]))
+		}
+		imageDir = args[3]
+	}
+	return Json2Confluence(args[0], args[1], imageDir)
+}
+
 func Command_heading(args []string) error {
 	if len(args) < 1 {
 		return errors.New("usage: heading input")
@@ -0,0 +1,466 @@
+package md2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// confluenceMacros are macros of admonitions by their level, the rest
+// are info.
+var confluenceMacros = map[string]string{
+	"tip":       "tip",
+	"hint":      "tip",
+	"success":   "tip",
+	"warning":   "note",
+	"caution":   "note",
+	"attention": "note",
+	"danger":    "warning",
+	"error":     "warning",
+	"failure":   "warning",
+	"bug":       "warning",
+}
+
+// ConfluenceOptions tune pages of Confluence storage format.
+type ConfluenceOptions struct {
+	// ImageDir is where src of images is looked up
+	ImageDir string
+	// Titles are titles of pages by their path, links to them become
+	// links to Confluence pages.
+	Titles map[string]string
+	// Path of the document for errors
+	Path string
+}
+
+// ConfluenceAttachment is image the page refers to by its name
+type ConfluenceAttachment struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// confluencePage is entry of the attachment manifest
+type confluencePage struct {
+	Title       string                 `json:"title"`
+	File        string                 `json:"file"`
+	Attachments []ConfluenceAttachment `json:"attachments"`
+}
+
+// confluenceWriter renders nodes as Confluence storage format and
+// collects images to be attached to the page.
+type confluenceWriter struct {
+	opts        ConfluenceOptions
+	source      string
+	attachments []ConfluenceAttachment
+	// footnotes are numbers of footnotes by their id
+	footnotes map[string]int
+}
+
+// confluenceCdata puts text to CDATA section, which can't contain "]]>".
+func confluenceCdata(text string) string {
+	return "<![CDATA[" + strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>") + "]]>"
+}
+
+func confluenceParameter(name string, value string) string {
+	return fmt.Sprintf(`<ac:parameter ac:name="%s">%s</ac:parameter>`, name, html.EscapeString(value))
+}
+
+// Confluence renders the document as Confluence storage format with the
+// attachments it refers to. The first heading is left out, it is the
+// title of the page.
+func Confluence(n *Node, opts ConfluenceOptions) ([]byte, []ConfluenceAttachment, error) {
+	doc, footnotes := splitFootnotes(n)
+	w := &confluenceWriter{opts: opts, source: n.Source(opts.Path), footnotes: map[string]int{}}
+	for i, fn := range footnotes {
+		w.footnotes[fn.Attributes["id"]] = i + 1
+	}
+	for i, ch := range doc.Children {
+		if ch.Type == Heading && ch.Attributes["level"] == "1" {
+			doc.Children = append(doc.Children[:i:i], doc.Children[i+1:]...)
+			break
+		}
+	}
+	var text bytes.Buffer
+	body, err := w.blocks(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	text.WriteString(body)
+	if len(footnotes) > 0 {
+		text.WriteString("<hr />\n<ol>\n")
+		for _, fn := range footnotes {
+			body, err := w.itemBody(&fn)
+			if err != nil {
+				return nil, nil, err
+			}
+			text.WriteString("<li>" + body + "</li>\n")
+		}
+		text.WriteString("</ol>\n")
+	}
+	return text.Bytes(), w.attachments, nil
+}
+
+func (w *confluenceWriter) blocks(n *Node) (string, error) {
+	var text strings.Builder
+	for _, ch := range n.Children {
+		block, err := w.node(&ch)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(block)
+	}
+	return text.String(), nil
+}
+
+// inliner writes content of emphasis-like node, which is either plain
+// literal or nested inline nodes.
+func (w *confluenceWriter) inliner(n *Node, tag string) (string, error) {
+	inner := html.EscapeString(n.Literal)
+	if n.Children != nil {
+		var err error
+		inner, err = w.blocks(n)
+		if err != nil {
+			return "", err
+		}
+	}
+	return "<" + tag + ">" + inner + "</" + tag + ">", nil
+}
+
+func (w *confluenceWriter) node(n *Node) (string, error) {
+	switch n.Type {
+	case Paragraph:
+		inlines, err := w.blocks(n)
+		return "<p>" + inlines + "</p>\n", err
+	case Text:
+		return html.EscapeString(n.Literal), nil
+	case Comment:
+		return "", nil
+	case Image:
+		return w.image(n)
+	case Link:
+		return w.link(n)
+	case Emphasis:
+		return w.inliner(n, "em")
+	case Bold:
+		return w.inliner(n, "strong")
+	case Strikethrough:
+		return w.inliner(n, "s")
+	case Code:
+		return "<code>" + html.EscapeString(n.Literal) + "</code>", nil
+	case Heading:
+		level, err := strconv.Atoi(n.Attributes["level"])
+		if err != nil || level < 1 || level > 6 {
+			level = 3
+		}
+		// anchor macro keeps ids of headings, which links lead to
+		anchor := ""
+		if id, ok := n.Attributes["id"]; ok {
+			anchor = `<ac:structured-macro ac:name="anchor">` + confluenceParameter("", id) + "</ac:structured-macro>"
+		}
+		return fmt.Sprintf("<h%d>%s%s</h%d>\n", level, anchor, html.EscapeString(n.Literal), level), nil
+	case List:
+		return w.list(n)
+	case Admonition:
+		macro, ok := confluenceMacros[n.Attributes["level"]]
+		if !ok {
+			macro = "info"
+		}
+		blocks, err := w.blocks(n)
+		title := ""
+		_, collapsible := n.Attributes["collapsible"]
+		if t, ok := n.Attributes["title"]; ok && t != "" && !collapsible {
+			title = confluenceParameter("title", t)
+		}
+		text := fmt.Sprintf("<ac:structured-macro ac:name=\"%s\">%s<ac:rich-text-body>\n%s</ac:rich-text-body></ac:structured-macro>\n",
+			macro, title, blocks)
+		if collapsible {
+			// collapsible admonitions are hidden by expand macro titled
+			// instead of them
+			text = fmt.Sprintf("<ac:structured-macro ac:name=\"expand\">%s<ac:rich-text-body>\n%s</ac:rich-text-body></ac:structured-macro>\n",
+				confluenceParameter("title", n.AdmonitionTitle()), text)
+		}
+		return text, err
+	case Tabs:
+		// tabs are expand macros titled like them, one after another
+		var text strings.Builder
+		for _, tab := range n.Children {
+			blocks, err := w.blocks(&tab)
+			if err != nil {
+				return "", err
+			}
+			text.WriteString(fmt.Sprintf("<ac:structured-macro ac:name=\"expand\">%s<ac:rich-text-body>\n%s</ac:rich-text-body></ac:structured-macro>\n",
+				confluenceParameter("title", tab.Attributes["title"]), blocks))
+		}
+		return text.String(), nil
+	case CodeFence:
+		return w.code(n.Literal, n.Attributes), nil
+	case HTML:
+		return w.tag(n)
+	case Table:
+		return w.table(n)
+	case Blockquote:
+		blocks, err := w.blocks(n)
+		return "<blockquote>\n" + blocks + "</blockquote>\n", err
+	case FootnoteRef:
+		return fmt.Sprintf("<sup>%d</sup>", w.footnotes[n.Attributes["id"]]), nil
+	case ThematicBreak:
+		return "<hr />\n", nil
+	case LineBreak:
+		return "<br />\n", nil
+	case "NewPage":
+		return "", nil
+	default:
+		fmt.Println("Type", n.Type)
+	}
+	return "", nil
+}
+
+// code writes code macro with language and title of the fence.
+func (w *confluenceWriter) code(literal string, attrs AttributeMap) string {
+	var text strings.Builder
+	text.WriteString(`<ac:structured-macro ac:name="code">`)
+	lang := strings.SplitN(attrs["lang"], " ", 2)[0]
+	if lang != "" {
+		text.WriteString(confluenceParameter("language", lang))
+	}
+	if title, ok := attrs["title"]; ok {
+		text.WriteString(confluenceParameter("title", title))
+	}
+	if _, ok := attrs["linenums"]; ok {
+		text.WriteString(confluenceParameter("linenumbers", "true"))
+	}
+	text.WriteString("<ac:plain-text-body>" + confluenceCdata(strings.TrimSuffix(literal, "\n")) + "</ac:plain-text-body>")
+	text.WriteString("</ac:structured-macro>\n")
+	return text.String()
+}
+
+// tag writes html passed through from markdown. Tags known to
+// preprocessors are written the way they are meant to be seen.
+func (w *confluenceWriter) tag(n *Node) (string, error) {
+	switch n.Attributes["tag"] {
+	case "if":
+		return w.blocks(n)
+	case "br":
+		return "<br />", nil
+	case "link":
+		link := Node{Type: Link, Literal: n.Literal, Attributes: n.Attributes}
+		return w.link(&link)
+	case "graphviz":
+		return w.code(n.Literal, AttributeMap{"lang": "dot"}), nil
+	}
+	return string(writeHtmlTag(n)), nil
+}
+
+// link makes links to pages with known titles Confluence page links,
+// links inside the page lead to its anchors.
+func (w *confluenceWriter) link(n *Node) (string, error) {
+	text := html.EscapeString(n.Literal)
+	if _, ok := n.Attributes["autolink"]; ok {
+		text = html.EscapeString(n.Attributes["href"])
+	}
+	href, hasHref := n.Attributes["href"]
+	anchor, hasAnchor := n.Attributes["anchor"]
+	if strings.Contains(href, "://") || strings.HasPrefix(href, "mailto:") {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(linkUrl(n)), text), nil
+	}
+	var link strings.Builder
+	link.WriteString("<ac:link")
+	if hasAnchor {
+		link.WriteString(fmt.Sprintf(` ac:anchor="%s"`, html.EscapeString(anchor)))
+	}
+	link.WriteString(">")
+	if hasHref {
+		title, ok := w.opts.Titles[filepath.Clean(href)]
+		if !ok {
+			return "", errors.New(fmt.Sprintf("File %s has link to page %s which is not exported", n.Location(w.source), href))
+		}
+		link.WriteString(fmt.Sprintf(`<ri:page ri:content-title="%s" />`, html.EscapeString(title)))
+	}
+	link.WriteString("<ac:link-body>" + text + "</ac:link-body></ac:link>")
+	return link.String(), nil
+}
+
+// image refers to attachment of the page, remote images stay urls.
+func (w *confluenceWriter) image(n *Node) (string, error) {
+	src := n.Attributes["src"]
+	alt := html.EscapeString(n.Literal)
+	if strings.Contains(src, "://") {
+		return fmt.Sprintf(`<ac:image ac:alt="%s"><ri:url ri:value="%s" /></ac:image>`, alt, html.EscapeString(src)), nil
+	}
+	path := filepath.Join(w.opts.ImageDir, src)
+	if _, err := os.Stat(path); err != nil {
+		return "", errors.New(fmt.Sprintf("File %s has invalid link to image %s", n.Location(w.source), src))
+	}
+	// attachments of the page are flat, their names must differ
+	name := filepath.Base(src)
+	known := false
+	for _, a := range w.attachments {
+		if a.Name == name && a.Path != path {
+			return "", errors.New(fmt.Sprintf("File %s has image %s with the same name as %s", n.Location(w.source), src, a.Path))
+		}
+		known = known || a.Name == name
+	}
+	if !known {
+		w.attachments = append(w.attachments, ConfluenceAttachment{Name: name, Path: path})
+	}
+	return fmt.Sprintf(`<ac:image ac:alt="%s"><ri:attachment ri:filename="%s" /></ac:image>`, alt, html.EscapeString(name)), nil
+}
+
+// list writes task list macro when items have checkboxes.
+func (w *confluenceWriter) list(n *Node) (string, error) {
+	var text strings.Builder
+	tasks := false
+	for _, item := range n.Children {
+		if _, ok := item.Attributes["checked"]; ok {
+			tasks = true
+		}
+	}
+	if tasks {
+		text.WriteString("<ac:task-list>\n")
+		for _, item := range n.Children {
+			status := "incomplete"
+			if item.Attributes["checked"] == "true" {
+				status = "complete"
+			}
+			body, err := w.itemBody(&item)
+			if err != nil {
+				return "", err
+			}
+			text.WriteString("<ac:task><ac:task-status>" + status + "</ac:task-status><ac:task-body>" + body + "</ac:task-body></ac:task>\n")
+		}
+		text.WriteString("</ac:task-list>\n")
+		return text.String(), nil
+	}
+	tag := "ul"
+	if _, ordered := n.Attributes["ordered"]; ordered {
+		tag = "ol"
+	}
+	text.WriteString("<" + tag + ">\n")
+	for _, item := range n.Children {
+		body, err := w.itemBody(&item)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString("<li>" + body + "</li>\n")
+	}
+	text.WriteString("</" + tag + ">\n")
+	return text.String(), nil
+}
+
+// itemBody writes content of list item, text of tight items goes
+// without paragraph.
+func (w *confluenceWriter) itemBody(item *Node) (string, error) {
+	if len(item.Children) == 1 && item.Children[0].Type == Paragraph {
+		return w.blocks(&item.Children[0])
+	}
+	return w.blocks(item)
+}
+
+func (w *confluenceWriter) table(n *Node) (string, error) {
+	var text strings.Builder
+	header := n.Children[0]
+	body := n.Children[1]
+	aligns := []string{}
+	text.WriteString("<table>\n<tbody>\n<tr>")
+	for _, th := range header.Children {
+		align := th.Attributes["align"]
+		aligns = append(aligns, align)
+		text.WriteString(htmlCellTag("th", align))
+		if th.Type == Text {
+			text.WriteString(html.EscapeString(th.Literal))
+		} else {
+			cell, err := w.blocks(&th)
+			if err != nil {
+				return "", err
+			}
+			text.WriteString(cell)
+		}
+		text.WriteString("</th>")
+	}
+	text.WriteString("</tr>\n")
+	for _, row := range body.Children {
+		text.WriteString("<tr>")
+		for i, cell := range row.Children {
+			align := ""
+			if i < len(aligns) {
+				align = aligns[i]
+			}
+			text.WriteString(htmlCellTag("td", align))
+			inlines, err := w.blocks(&cell)
+			if err != nil {
+				return "", err
+			}
+			text.WriteString(inlines)
+			text.WriteString("</td>")
+		}
+		text.WriteString("</tr>\n")
+	}
+	text.WriteString("</tbody>\n</table>\n")
+	return text.String(), nil
+}
+
+// checkConfluence ensures that page is well-formed XML as Confluence
+// requires.
+func checkConfluence(page []byte) error {
+	return checkXml([]byte(`<page xmlns:ac="http://atlassian.com/content" xmlns:ri="http://atlassian.com/resource/identifier">` + string(page) + "</page>"))
+}
+
+// Json2Confluence exports pages of planarized directory to Confluence
+// storage format, one file per page. Links between pages lead to pages
+// with titles set by Planarize. attachments.json lists images each page
+// needs to have attached.
+func Json2Confluence(inDir string, outDir string, imageDir string) error {
+	paths := ListAllMd(inDir)
+	docs := map[string]Node{}
+	titles := map[string]string{}
+	for _, fp := range paths {
+		doc, err := ReadJson(fp)
+		if err != nil {
+			return err
+		}
+		title, ok := doc.Attributes["title"]
+		if !ok {
+			return errors.New(fmt.Sprintf("No title in %s, the directory must be planarized", fp))
+		}
+		rel, _ := filepath.Rel(inDir, fp)
+		docs[fp] = doc
+		titles[filepath.Clean(rel)] = title
+	}
+
+	os.MkdirAll(outDir, os.ModePerm)
+	manifest := []confluencePage{}
+	for _, fp := range paths {
+		doc := docs[fp]
+		rel, _ := filepath.Rel(inDir, fp)
+		page, attachments, err := Confluence(&doc, ConfluenceOptions{ImageDir: imageDir, Titles: titles, Path: fp})
+		if err != nil {
+			return err
+		}
+		err = checkConfluence(page)
+		if err != nil {
+			// html passed through from markdown must be well-formed too
+			return errors.New(fmt.Sprintf("Page %s is not valid storage format: %v", doc.Source(fp), err))
+		}
+		file := strings.TrimSuffix(filepath.ToSlash(rel), ".md") + ".xml"
+		output := filepath.Join(outDir, file)
+		os.MkdirAll(filepath.Dir(output), os.ModePerm)
+		err = os.WriteFile(output, page, os.ModePerm)
+		if err != nil {
+			return err
+		}
+		if attachments == nil {
+			attachments = []ConfluenceAttachment{}
+		}
+		manifest = append(manifest, confluencePage{Title: titles[filepath.Clean(rel)], File: file, Attachments: attachments})
+	}
+	text, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outDir, "attachments.json"), text, os.ModePerm)
+}
@@ -0,0 +1,45 @@
+package md2json_test
+
+import (
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJson2Confluence(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+	os.MkdirAll(filepath.Join(docs, "img"), os.ModePerm)
+	for _, fp := range md2json.ListAllMd("testdata/confluence/docs") {
+		err := md2json.Md2Json(fp, filepath.Join(docs, filepath.Base(fp)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(docs, "img", "logo.png"), []byte("png"), os.ModePerm)
+
+	out := filepath.Join(dir, "out")
+	err := md2json.Json2Confluence(docs, out, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"install.xml", "usage.xml", "attachments.json"} {
+		actual, _ := os.ReadFile(filepath.Join(out, name))
+		text := strings.ReplaceAll(string(actual), dir+"/", "")
+		expected, _ := os.ReadFile(filepath.Join("testdata/confluence", name))
+		if text != string(expected) {
+			t.Errorf("Json2Confluence() %s\nactual\n%s\nexpected\n%s", name, text, expected)
+		}
+	}
+}
+
+func TestJson2ConfluenceUnknownPage(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte("# Page\n\nSee [other](other.md).\n"))
+	_, _, err := md2json.Confluence(&doc, md2json.ConfluenceOptions{Titles: map[string]string{}, Path: "page.md"})
+	expected := "File page.md:3:5 has link to page other.md which is not exported"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Confluence() error %v, expected %s", err, expected)
+	}
+}
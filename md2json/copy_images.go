@@ -13,7 +13,7 @@ func CopyImages(srcDir string, imageDir string, outDir string) error {
 		if err != nil {
 			return err
 		}
-		err = copyRequiredImages(&doc, fp, imageDir, outDir)
+		err = copyRequiredImages(&doc, doc.Source(fp), imageDir, outDir)
 		if err != nil {
 			return err
 		}
@@ -28,18 +28,26 @@ func copyRequiredImages(n *Node, path string, imageDir string, outDir string) er
 	if n.Type == Image {
 		src, ok := n.Attributes["src"]
 		if !ok {
-			return errors.New(fmt.Sprintf("File %s has image without src", path))
+			return errors.New(fmt.Sprintf("File %s has image without src", n.Location(path)))
 		}
-		sourceFile := filepath.Join(imageDir, src)
-		destFile := filepath.Join(outDir, src)
-		imageBody, err := os.ReadFile(sourceFile)
-		if err != nil {
-			return errors.New(fmt.Sprintf("File %s has invalid link to image %s", path, src))
+		files := []string{src}
+		// pdf copy of svg made by svg2pdf
+		pdf, ok := n.Attributes["pdf"]
+		if ok {
+			files = append(files, pdf)
 		}
-		os.MkdirAll(filepath.Dir(destFile), os.ModePerm)
-		err = os.WriteFile(destFile, imageBody, os.ModePerm)
-		if err != nil {
-			return err
+		for _, src := range files {
+			sourceFile := filepath.Join(imageDir, src)
+			destFile := filepath.Join(outDir, src)
+			imageBody, err := os.ReadFile(sourceFile)
+			if err != nil {
+				return errors.New(fmt.Sprintf("File %s has invalid link to image %s", n.Location(path), src))
+			}
+			os.MkdirAll(filepath.Dir(destFile), os.ModePerm)
+			err = os.WriteFile(destFile, imageBody, os.ModePerm)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	if n.Children != nil {
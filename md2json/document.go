@@ -1,5 +1,10 @@
 package md2json
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Kind string
 type AttributeMap map[string]string
 
@@ -24,13 +29,61 @@ const (
 	TableBody  Kind = "TBody"
 	TableRow   Kind = "Row"
 	TableCell  Kind = "Cell"
+	Blockquote Kind = "Blockquote"
+	// ThematicBreak is a horizontal rule: ---, *** or ___
+	ThematicBreak Kind = "ThematicBreak"
+	// LineBreak is a hard line break: two trailing spaces or backslash
+	LineBreak     Kind = "LineBreak"
+	Strikethrough Kind = "Strikethrough"
+	// FootnoteRef is [^id] in text, FootnoteDef is [^id]: text block
+	FootnoteRef Kind = "FootnoteRef"
+	FootnoteDef Kind = "FootnoteDef"
+	// Tabs are content tabs === "Title", every Tab has title and blocks
+	Tabs Kind = "Tabs"
+	Tab  Kind = "Tab"
 )
 
+// Point is a location in the markdown source: 1-based line and column,
+// where column counts characters, not bytes.
+type Point struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Position spans a node in the markdown source. End points right after
+// the last character of the node.
+type Position struct {
+	Start Point `json:"start"`
+	End   Point `json:"end"`
+}
+
 type Node struct {
 	Type       Kind         `json:"type"`
 	Children   []Node       `json:"children,omitempty"`
 	Literal    string       `json:"text,omitempty"`
 	Attributes AttributeMap `json:"attributes,omitempty"`
+	Position   *Position    `json:"position,omitempty"`
+}
+
+// Location formats place of the node for error messages as file:line:col.
+// Nodes without position (i.e. created by preprocessors) give just file.
+func (self *Node) Location(path string) string {
+	if self.Position == nil {
+		return path
+	}
+	return fmt.Sprintf("%s:%d:%d", path, self.Position.Start.Line, self.Position.Start.Column)
+}
+
+// Source returns path to the markdown file the document was parsed from.
+// Planarized documents remember it in the original attribute.
+func (self *Node) Source(path string) string {
+	if self.Attributes != nil {
+		original, ok := self.Attributes["original"]
+		if ok {
+			return original + ".md"
+		}
+	}
+	return path
 }
 
 func (self *Node) Heading() (string, string, bool) {
@@ -56,3 +109,16 @@ func (self *Node) Heading() (string, string, bool) {
 	}
 	return searchHeading(self)
 }
+
+// AdmonitionTitle returns title shown on the admonition: the title
+// attribute or capitalized level. Empty title means no title.
+func (self *Node) AdmonitionTitle() string {
+	if title, ok := self.Attributes["title"]; ok {
+		return title
+	}
+	level := self.Attributes["level"]
+	if len(level) > 0 {
+		level = strings.ToUpper(level[:1]) + level[1:]
+	}
+	return level
+}
@@ -0,0 +1,662 @@
+package md2json
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DocxOptions tune Word document produced from the document.
+type DocxOptions struct {
+	// ImageDir is where src of images is looked up
+	ImageDir string
+	// Path of the document for errors
+	Path string
+}
+
+// docxWriter keeps parts of the package that blocks refer to: relations
+// of links and images, numbering of lists and bookmarks.
+type docxWriter struct {
+	opts      DocxOptions
+	source    string
+	rels      bytes.Buffer
+	relCount  int
+	media     []docxFile
+	types     map[string]string
+	nums      bytes.Buffer
+	numCount  int
+	bookmarks int
+	drawings  int
+	footnotes bytes.Buffer
+	noteCount int
+}
+
+type docxFile struct {
+	name string
+	body []byte
+}
+
+const docxMaxWidth = 6 * 914400 // 6 inches in EMU
+
+const docxNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+	`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" ` +
+	`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+	`xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"`
+
+const docxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>
+`
+
+func docxStyle(id string, kind string, name string, props string) string {
+	return fmt.Sprintf(`<w:style w:type="%s" w:styleId="%s"><w:name w:val="%s"/>%s</w:style>`, kind, id, name, props) + "\n"
+}
+
+// docxStyles are built-in Word styles used by the writer, so documents
+// look the same as made by Word and can be restyled by reviewers.
+func docxStyles() string {
+	var text strings.Builder
+	text.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	text.WriteString(`<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` + "\n")
+	text.WriteString(`<w:docDefaults><w:rPrDefault><w:rPr><w:sz w:val="22"/></w:rPr></w:rPrDefault><w:pPrDefault><w:pPr><w:spacing w:after="120"/></w:pPr></w:pPrDefault></w:docDefaults>` + "\n")
+	text.WriteString(docxStyle("Normal", "paragraph", "Normal", `<w:qFormat/>`))
+	sizes := []int{36, 32, 28, 26, 24, 22}
+	for i, size := range sizes {
+		text.WriteString(docxStyle(fmt.Sprintf("Heading%d", i+1), "paragraph", fmt.Sprintf("heading %d", i+1),
+			fmt.Sprintf(`<w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="%d"/></w:pPr><w:rPr><w:b/><w:sz w:val="%d"/></w:rPr>`, i, size)))
+	}
+	text.WriteString(docxStyle("Code", "paragraph", "Code",
+		`<w:basedOn w:val="Normal"/><w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/><w:spacing w:after="0"/></w:pPr><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/><w:sz w:val="20"/></w:rPr>`))
+	text.WriteString(docxStyle("CodeChar", "character", "Code Char",
+		`<w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/></w:rPr>`))
+	text.WriteString(docxStyle("Quote", "paragraph", "Quote",
+		`<w:basedOn w:val="Normal"/><w:pPr><w:ind w:left="720"/></w:pPr><w:rPr><w:i/></w:rPr>`))
+	text.WriteString(docxStyle("Admonition", "paragraph", "Admonition",
+		`<w:basedOn w:val="Normal"/><w:pPr><w:pBdr><w:left w:val="single" w:sz="24" w:space="8" w:color="448AFF"/></w:pBdr><w:ind w:left="360"/></w:pPr>`))
+	text.WriteString(docxStyle("ListParagraph", "paragraph", "List Paragraph",
+		`<w:basedOn w:val="Normal"/><w:pPr><w:ind w:left="720"/></w:pPr>`))
+	text.WriteString(docxStyle("Hyperlink", "character", "Hyperlink",
+		`<w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr>`))
+	text.WriteString(docxStyle("FootnoteText", "paragraph", "footnote text",
+		`<w:basedOn w:val="Normal"/><w:rPr><w:sz w:val="20"/></w:rPr>`))
+	text.WriteString(docxStyle("FootnoteReference", "character", "footnote reference",
+		`<w:rPr><w:vertAlign w:val="superscript"/></w:rPr>`))
+	text.WriteString(docxStyle("TableGrid", "table", "Table Grid",
+		`<w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:color="auto"/><w:left w:val="single" w:sz="4" w:color="auto"/><w:bottom w:val="single" w:sz="4" w:color="auto"/><w:right w:val="single" w:sz="4" w:color="auto"/><w:insideH w:val="single" w:sz="4" w:color="auto"/><w:insideV w:val="single" w:sz="4" w:color="auto"/></w:tblBorders></w:tblPr>`))
+	text.WriteString("</w:styles>\n")
+	return text.String()
+}
+
+// docxAbstractNum defines bullets (0) and decimal numbers (1) for all
+// nesting levels.
+func docxAbstractNum(id int, format string) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf(`<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="hybridMultilevel"/>`, id))
+	bullets := []string{"•", "◦", "▪"}
+	for lvl := 0; lvl < 9; lvl++ {
+		lvlText := fmt.Sprintf("%%%d.", lvl+1)
+		if format == "bullet" {
+			lvlText = bullets[lvl%len(bullets)]
+		}
+		text.WriteString(fmt.Sprintf(`<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="%s"/><w:lvlText w:val="%s"/><w:lvlJc w:val="left"/><w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr></w:lvl>`,
+			lvl, format, lvlText, 720*(lvl+1)))
+	}
+	text.WriteString("</w:abstractNum>\n")
+	return text.String()
+}
+
+func escapeXml(s string) string {
+	return html.EscapeString(s)
+}
+
+var docxBookmarkRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// docxBookmark makes Word bookmark name from heading id: letters, digits
+// and underscores, up to 40 characters.
+func docxBookmark(id string) string {
+	name := "h_" + docxBookmarkRegexp.ReplaceAllString(id, "_")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return name
+}
+
+func (w *docxWriter) addRel(kind string, target string, external bool) string {
+	w.relCount++
+	id := fmt.Sprintf("rId%d", w.relCount)
+	mode := ""
+	if external {
+		mode = ` TargetMode="External"`
+	}
+	w.rels.WriteString(fmt.Sprintf(`<Relationship Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/%s" Target="%s"%s/>`+"\n",
+		id, kind, escapeXml(target), mode))
+	return id
+}
+
+// addNum makes new numbering instance, so every list starts from its
+// own start number.
+func (w *docxWriter) addNum(ordered bool, level int, start int) int {
+	w.numCount++
+	abstract := 0
+	if ordered {
+		abstract = 1
+	}
+	w.nums.WriteString(fmt.Sprintf(`<w:num w:numId="%d"><w:abstractNumId w:val="%d"/>`, w.numCount, abstract))
+	if ordered {
+		w.nums.WriteString(fmt.Sprintf(`<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="%d"/></w:lvlOverride>`, level, start))
+	}
+	w.nums.WriteString("</w:num>\n")
+	return w.numCount
+}
+
+// docxRunOrder is the order of run properties required by the schema
+var docxRunOrder = []string{"<w:rStyle ", "<w:b/>", "<w:i/>", "<w:strike/>"}
+
+var docxPropRegexp = regexp.MustCompile(`<w:[^>]*/>`)
+
+// docxRun writes run with properties collected from enclosing nodes in
+// any order, like italic inside bold or bold inside italic.
+func docxRun(rPr string, inner string) string {
+	if rPr == "" {
+		return "<w:r>" + inner + "</w:r>"
+	}
+	props := docxPropRegexp.FindAllString(rPr, -1)
+	var sorted strings.Builder
+	for _, prefix := range docxRunOrder {
+		for _, p := range props {
+			if strings.HasPrefix(p, prefix) {
+				sorted.WriteString(p)
+				break
+			}
+		}
+	}
+	return "<w:r><w:rPr>" + sorted.String() + "</w:rPr>" + inner + "</w:r>"
+}
+
+func docxText(rPr string, text string) string {
+	return docxRun(rPr, `<w:t xml:space="preserve">`+escapeXml(text)+`</w:t>`)
+}
+
+// runs writes inline nodes with character properties of enclosing nodes.
+func (w *docxWriter) runs(n *Node, rPr string) (string, error) {
+	var text strings.Builder
+	for i := range n.Children {
+		r, err := w.run(&n.Children[i], rPr)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(r)
+	}
+	return text.String(), nil
+}
+
+// inliner writes content of emphasis-like node, which is either plain
+// literal or nested inline nodes.
+func (w *docxWriter) inliner(n *Node, rPr string) (string, error) {
+	if n.Children == nil {
+		return docxText(rPr, n.Literal), nil
+	}
+	return w.runs(n, rPr)
+}
+
+func (w *docxWriter) run(n *Node, rPr string) (string, error) {
+	switch n.Type {
+	case Text:
+		return docxText(rPr, n.Literal), nil
+	case Emphasis:
+		return w.inliner(n, rPr+"<w:i/>")
+	case Bold:
+		return w.inliner(n, rPr+"<w:b/>")
+	case Strikethrough:
+		return w.inliner(n, rPr+"<w:strike/>")
+	case Code:
+		return docxText(`<w:rStyle w:val="CodeChar"/>`+rPr, n.Literal), nil
+	case LineBreak:
+		return docxRun("", "<w:br/>"), nil
+	case Link:
+		return w.link(n, rPr)
+	case Image:
+		return w.image(n)
+	case FootnoteRef:
+		return w.footnote(n, rPr)
+	case HTML:
+		tag, _ := n.Attributes["tag"]
+		switch tag {
+		case "br":
+			return docxRun("", "<w:br/>"), nil
+		case "link":
+			link := Node{Type: Link, Literal: n.Literal, Attributes: n.Attributes}
+			return w.link(&link, rPr)
+		}
+		if n.Children != nil {
+			return w.runs(n, rPr)
+		}
+		return docxText(rPr, n.Literal), nil
+	case Comment:
+		return "", nil
+	default:
+		return "", errors.New(fmt.Sprintf("File %s has %s, which docx does not support", n.Location(w.source), n.Type))
+	}
+}
+
+func (w *docxWriter) link(n *Node, rPr string) (string, error) {
+	href, _ := n.Attributes["href"]
+	anchor, hasAnchor := n.Attributes["anchor"]
+	label := n.Literal
+	if _, ok := n.Attributes["autolink"]; ok {
+		label = href
+	}
+	runs := docxText(`<w:rStyle w:val="Hyperlink"/>`+rPr, label)
+	external := strings.Contains(href, "://") || strings.HasPrefix(href, "mailto:")
+	if hasAnchor && anchor != "" && !external {
+		// headings of other pages are not in this document, but pages
+		// assembled together keep their ids
+		return fmt.Sprintf(`<w:hyperlink w:anchor="%s">%s</w:hyperlink>`, docxBookmark(anchor), runs), nil
+	}
+	if !external {
+		// other pages without anchor have no place to lead to
+		return docxText(rPr, label), nil
+	}
+	id := w.addRel("hyperlink", linkUrl(n), true)
+	return fmt.Sprintf(`<w:hyperlink r:id="%s">%s</w:hyperlink>`, id, runs), nil
+}
+
+// image embeds png and jpeg images scaled to the page width. Other
+// images are written as their alt text.
+func (w *docxWriter) image(n *Node) (string, error) {
+	src, _ := n.Attributes["src"]
+	ext := strings.ToLower(filepath.Ext(src))
+	if strings.Contains(src, "://") || (ext != ".png" && ext != ".jpg" && ext != ".jpeg") {
+		return docxText("<w:i/>", fmt.Sprintf("[%s]", n.Literal)), nil
+	}
+	body, err := os.ReadFile(filepath.Join(w.opts.ImageDir, src))
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("File %s has invalid link to image %s", n.Location(w.source), src))
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("File %s has broken image %s: %v", n.Location(w.source), src, err))
+	}
+	w.drawings++
+	name := fmt.Sprintf("image%d%s", w.drawings, ext)
+	w.media = append(w.media, docxFile{"word/media/" + name, body})
+	w.types[strings.TrimPrefix(ext, ".")] = "image/" + strings.TrimPrefix(strings.ReplaceAll(ext, "jpg", "jpeg"), ".")
+	id := w.addRel("image", "media/"+name, false)
+	// 96 dpi
+	cx := config.Width * 9525
+	cy := config.Height * 9525
+	if cx > docxMaxWidth {
+		cy = cy * docxMaxWidth / cx
+		cx = docxMaxWidth
+	}
+	drawing := fmt.Sprintf(`<w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0"><wp:extent cx="%d" cy="%d"/><wp:docPr id="%d" name="Picture %d" descr="%s"/>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:pic><pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr></pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing>`,
+		cx, cy, w.drawings, w.drawings, escapeXml(n.Literal), w.drawings, name, id, cx, cy)
+	return docxRun("", drawing), nil
+}
+
+// footnote writes reference and puts content, attached to the reference
+// by attachFootnotes, into footnotes part. Word can't refer to one note
+// twice, so every reference gets its own copy of the note like in pandoc.
+// Reference without definition is written as text like in json2text.
+func (w *docxWriter) footnote(n *Node, rPr string) (string, error) {
+	if len(n.Children) == 0 {
+		return docxText(rPr, "["+n.Attributes["id"]+"]"), nil
+	}
+	w.noteCount++
+	id := w.noteCount
+	blocks, err := w.blocks(n, "FootnoteText", 0)
+	if err != nil {
+		return "", err
+	}
+	ref := docxRun(`<w:rStyle w:val="FootnoteReference"/>`, "<w:footnoteRef/>")
+	// reference mark goes into the first paragraph of the note
+	blocks = strings.Replace(blocks, "</w:pPr>", "</w:pPr>"+ref, 1)
+	w.footnotes.WriteString(fmt.Sprintf(`<w:footnote w:id="%d">%s</w:footnote>`+"\n", id, blocks))
+	return docxRun(`<w:rStyle w:val="FootnoteReference"/>`, fmt.Sprintf(`<w:footnoteReference w:id="%d"/>`, id)), nil
+}
+
+func docxParagraph(pPr string, runs string) string {
+	return "<w:p><w:pPr>" + pPr + "</w:pPr>" + runs + "</w:p>\n"
+}
+
+func docxStyleProp(style string) string {
+	if style == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<w:pStyle w:val="%s"/>`, style)
+}
+
+// blocks writes block children of n with paragraph style and list
+// indentation of enclosing nodes.
+func (w *docxWriter) blocks(n *Node, style string, depth int) (string, error) {
+	var text strings.Builder
+	for i := range n.Children {
+		b, err := w.block(&n.Children[i], style, depth)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(b)
+	}
+	return text.String(), nil
+}
+
+func (w *docxWriter) block(n *Node, style string, depth int) (string, error) {
+	indent := ""
+	if depth > 0 && style == "" {
+		style = "ListParagraph"
+		indent = fmt.Sprintf(`<w:ind w:left="%d"/>`, 720*depth)
+	}
+	switch n.Type {
+	case Paragraph:
+		runs, err := w.runs(n, "")
+		if err != nil {
+			return "", err
+		}
+		return docxParagraph(docxStyleProp(style)+indent, runs), nil
+	case Heading:
+		return w.heading(n), nil
+	case List:
+		return w.list(n, style, depth)
+	case CodeFence:
+		lines := strings.Split(strings.TrimSuffix(n.Literal, "\n"), "\n")
+		var runs strings.Builder
+		for i, line := range lines {
+			if i > 0 {
+				runs.WriteString(docxRun("", "<w:br/>"))
+			}
+			runs.WriteString(docxText("", line))
+		}
+		return docxParagraph(docxStyleProp("Code")+indent, runs.String()), nil
+	case Blockquote:
+		return w.blocks(n, "Quote", depth)
+	case Admonition:
+		body, err := w.blocks(n, "Admonition", depth)
+		if err != nil {
+			return "", err
+		}
+		title := n.AdmonitionTitle()
+		if title == "" {
+			return body, nil
+		}
+		return docxParagraph(docxStyleProp("Admonition")+indent, docxText("<w:b/>", title)) + body, nil
+	case Tabs:
+		// every tab is bold title paragraph followed by its blocks
+		var text strings.Builder
+		for _, tab := range n.Children {
+			body, err := w.blocks(&tab, style, depth)
+			if err != nil {
+				return "", err
+			}
+			text.WriteString(docxParagraph(docxStyleProp(style)+indent, docxText("<w:b/>", tab.Attributes["title"])))
+			text.WriteString(body)
+		}
+		return text.String(), nil
+	case Table:
+		return w.table(n)
+	case ThematicBreak:
+		return docxParagraph(`<w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr>`, ""), nil
+	case "NewPage":
+		return docxParagraph("", docxRun("", `<w:br w:type="page"/>`)), nil
+	case Comment:
+		return "", nil
+	case HTML:
+		tag, _ := n.Attributes["tag"]
+		if tag == "if" {
+			return w.blocks(n, style, depth)
+		}
+	}
+	// other blocks are inline nodes, run reports unknown ones
+	runs, err := w.run(n, "")
+	if err != nil || runs == "" {
+		return "", err
+	}
+	return docxParagraph(docxStyleProp(style)+indent, runs), nil
+}
+
+func (w *docxWriter) heading(n *Node) string {
+	level, err := strconv.Atoi(n.Attributes["level"])
+	if err != nil {
+		level = 3
+	}
+	if level < 1 {
+		level = 1
+	}
+	if level > 6 {
+		level = 6
+	}
+	runs := docxText("", n.Literal)
+	id, ok := n.Attributes["id"]
+	if ok {
+		w.bookmarks++
+		runs = fmt.Sprintf(`<w:bookmarkStart w:id="%d" w:name="%s"/>%s<w:bookmarkEnd w:id="%d"/>`, w.bookmarks, docxBookmark(id), runs, w.bookmarks)
+	}
+	return docxParagraph(docxStyleProp(fmt.Sprintf("Heading%d", level)), runs)
+}
+
+// list writes items as numbered paragraphs, nested content of items is
+// indented to the text of the item.
+func (w *docxWriter) list(n *Node, style string, depth int) (string, error) {
+	_, ordered := n.Attributes["ordered"]
+	start, err := strconv.Atoi(n.Attributes["start"])
+	if err != nil {
+		start = 1
+	}
+	numId := w.addNum(ordered, depth, start)
+	var text strings.Builder
+	for _, item := range n.Children {
+		prefix := ""
+		switch item.Attributes["checked"] {
+		case "true":
+			prefix = "☒ "
+		case "false":
+			prefix = "☐ "
+		}
+		numPr := fmt.Sprintf(`<w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, depth, numId)
+		rest := item.Children
+		runs := ""
+		if len(rest) > 0 && rest[0].Type == Paragraph {
+			runs, err = w.runs(&rest[0], "")
+			if err != nil {
+				return "", err
+			}
+			rest = rest[1:]
+		}
+		if prefix != "" {
+			runs = docxText("", prefix) + runs
+		}
+		text.WriteString(docxParagraph(numPr, runs))
+		nested, err := w.blocks(&Node{Children: rest}, style, depth+1)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(nested)
+	}
+	return text.String(), nil
+}
+
+func (w *docxWriter) table(n *Node) (string, error) {
+	var text strings.Builder
+	header := n.Children[0]
+	body := n.Children[1]
+	columns := len(header.Children)
+	widths := texColumnWidths(n, columns)
+	text.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	// 9638 twips is the text width of A4 page with 2 cm margins
+	for _, width := range widths {
+		text.WriteString(fmt.Sprintf(`<w:gridCol w:w="%d"/>`, int(width*9638)))
+	}
+	text.WriteString("</w:tblGrid>\n")
+	cell := func(n *Node, align string, rPr string) (string, error) {
+		var runs string
+		var err error
+		if n.Type == Text {
+			runs = docxText(rPr, n.Literal)
+		} else {
+			runs, err = w.runs(n, rPr)
+		}
+		jc := ""
+		switch align {
+		case "center":
+			jc = `<w:jc w:val="center"/>`
+		case "right":
+			jc = `<w:jc w:val="right"/>`
+		}
+		return "<w:tc>" + strings.TrimSuffix(docxParagraph(jc, runs), "\n") + "</w:tc>", err
+	}
+	aligns := make([]string, columns)
+	text.WriteString("<w:tr><w:trPr><w:tblHeader/></w:trPr>")
+	for i := range header.Children {
+		aligns[i] = header.Children[i].Attributes["align"]
+		c, err := cell(&header.Children[i], aligns[i], "<w:b/>")
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(c)
+	}
+	text.WriteString("</w:tr>\n")
+	for _, row := range body.Children {
+		text.WriteString("<w:tr>")
+		for i := range row.Children {
+			align := ""
+			if i < columns {
+				align = aligns[i]
+			}
+			c, err := cell(&row.Children[i], align, "")
+			if err != nil {
+				return "", err
+			}
+			text.WriteString(c)
+		}
+		text.WriteString("</w:tr>\n")
+	}
+	text.WriteString("</w:tbl>\n")
+	// Word merges adjacent tables without paragraph between them
+	text.WriteString("<w:p/>\n")
+	return text.String(), nil
+}
+
+// Docx writes the document as Office Open XML package.
+func Docx(n *Node, opts DocxOptions) ([]byte, error) {
+	doc, footnotes := splitFootnotes(n)
+	attachFootnotes(&doc, footnotes)
+	w := &docxWriter{
+		opts:   opts,
+		source: n.Source(opts.Path),
+		types:  map[string]string{},
+	}
+	// fixed relations of the document go first
+	w.addRel("styles", "styles.xml", false)
+	w.addRel("numbering", "numbering.xml", false)
+	w.addRel("footnotes", "footnotes.xml", false)
+	body, err := w.blocks(&doc, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var document bytes.Buffer
+	document.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	document.WriteString("<w:document " + docxNamespaces + ">\n<w:body>\n")
+	document.WriteString(body)
+	document.WriteString(`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr>` + "\n")
+	document.WriteString("</w:body>\n</w:document>\n")
+
+	var numbering bytes.Buffer
+	numbering.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	numbering.WriteString(`<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` + "\n")
+	numbering.WriteString(docxAbstractNum(0, "bullet"))
+	numbering.WriteString(docxAbstractNum(1, "decimal"))
+	numbering.Write(w.nums.Bytes())
+	numbering.WriteString("</w:numbering>\n")
+
+	var notes bytes.Buffer
+	notes.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	notes.WriteString("<w:footnotes " + docxNamespaces + ">\n")
+	notes.WriteString(`<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` + "\n")
+	notes.WriteString(`<w:footnote w:type="continuationSeparator" w:id="0"><w:p><w:r><w:continuationSeparator/></w:r></w:p></w:footnote>` + "\n")
+	notes.Write(w.footnotes.Bytes())
+	notes.WriteString("</w:footnotes>\n")
+
+	var rels bytes.Buffer
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + "\n")
+	rels.Write(w.rels.Bytes())
+	rels.WriteString("</Relationships>\n")
+
+	var types bytes.Buffer
+	types.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	types.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` + "\n")
+	types.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` + "\n")
+	types.WriteString(`<Default Extension="xml" ContentType="application/xml"/>` + "\n")
+	for _, ext := range []string{"png", "jpg", "jpeg"} {
+		if t, ok := w.types[ext]; ok {
+			types.WriteString(fmt.Sprintf(`<Default Extension="%s" ContentType="%s"/>`+"\n", ext, t))
+		}
+	}
+	parts := map[string]string{
+		"document":  "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml",
+		"styles":    "application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml",
+		"numbering": "application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml",
+		"footnotes": "application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml",
+	}
+	for _, part := range []string{"document", "styles", "numbering", "footnotes"} {
+		types.WriteString(fmt.Sprintf(`<Override PartName="/word/%s.xml" ContentType="%s"/>`+"\n", part, parts[part]))
+	}
+	types.WriteString("</Types>\n")
+
+	var pkg bytes.Buffer
+	z := zip.NewWriter(&pkg)
+	files := []docxFile{
+		{"[Content_Types].xml", types.Bytes()},
+		{"_rels/.rels", []byte(docxRootRels)},
+		{"word/document.xml", document.Bytes()},
+		{"word/styles.xml", []byte(docxStyles())},
+		{"word/numbering.xml", numbering.Bytes()},
+		{"word/footnotes.xml", notes.Bytes()},
+		{"word/_rels/document.xml.rels", rels.Bytes()},
+	}
+	files = append(files, w.media...)
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = fw.Write(f.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = z.Close()
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Bytes(), nil
+}
+
+// Json2Docx writes document as Word file. Images are looked up relative
+// to imageDir.
+func Json2Docx(input string, output string, imageDir string) error {
+	doc, err := ReadJson(input)
+	if err != nil {
+		return err
+	}
+	docx, err := Docx(&doc, DocxOptions{ImageDir: imageDir, Path: input})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, docx, os.ModePerm)
+}
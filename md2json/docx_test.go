@@ -0,0 +1,137 @@
+package md2json_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readDocx(t *testing.T, docx []byte) map[string]string {
+	r, err := zip.NewReader(bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range r.File {
+		rc, _ := f.Open()
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+		if strings.HasSuffix(f.Name, ".xml") || strings.HasSuffix(f.Name, ".rels") {
+			d := xml.NewDecoder(bytes.NewReader(body))
+			for {
+				_, err := d.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Errorf("%s is not well-formed: %v", f.Name, err)
+					break
+				}
+			}
+		}
+	}
+	return files
+}
+
+func TestDocx(t *testing.T) {
+	dir := t.TempDir()
+	var img bytes.Buffer
+	png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 20, 10)))
+	os.WriteFile(filepath.Join(dir, "scheme.png"), img.Bytes(), os.ModePerm)
+
+	input := "# Setup & run {#setup}\n\n" +
+		"Text with **bold *both* more** and `code`, see [site](https://example.com/a?b=1&c=2) or [above](#setup)[^1].\n\n" +
+		"![Scheme](scheme.png)\n\n" +
+		"3. First\n4. Second\n\n    * nested\n\n" +
+		"| Name | Value |\n|:-----|:-----:|\n| port | `80` |\n\n" +
+		"```\nline 1\n  line <2>\n```\n\n" +
+		"=== \"Linux\"\n\n    Run it.\n\n" +
+		"[^1]: The note.\n"
+	doc := md2json.MarkdownParse([]byte(input))
+	docx, err := md2json.Docx(&doc, md2json.DocxOptions{ImageDir: dir, Path: "page.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readDocx(t, docx)
+	document := files["word/document.xml"]
+	for _, expected := range []string{
+		`<w:pStyle w:val="Heading1"/></w:pPr><w:bookmarkStart w:id="1" w:name="h_setup"/><w:r><w:t xml:space="preserve">Setup &amp; run</w:t></w:r><w:bookmarkEnd w:id="1"/>`,
+		`<w:r><w:rPr><w:b/><w:i/></w:rPr><w:t xml:space="preserve">both</w:t></w:r>`,
+		`<w:r><w:rPr><w:rStyle w:val="CodeChar"/></w:rPr><w:t xml:space="preserve">code</w:t></w:r>`,
+		`<w:hyperlink r:id="rId4"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t xml:space="preserve">site</w:t></w:r></w:hyperlink>`,
+		`<w:hyperlink w:anchor="h_setup">`,
+		`<w:footnoteReference w:id="1"/>`,
+		`<wp:extent cx="190500" cy="95250"/>`,
+		`<w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">First</w:t></w:r>`,
+		`<w:numPr><w:ilvl w:val="1"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">nested</w:t></w:r>`,
+		`<w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p><w:pPr></w:pPr><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Name</w:t></w:r></w:p></w:tc>`,
+		`<w:tc><w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:rStyle w:val="CodeChar"/></w:rPr><w:t xml:space="preserve">80</w:t></w:r></w:p></w:tc>`,
+		`<w:pStyle w:val="Code"/></w:pPr><w:r><w:t xml:space="preserve">line 1</w:t></w:r><w:r><w:br/></w:r><w:r><w:t xml:space="preserve">  line &lt;2&gt;</w:t></w:r>`,
+		`<w:p><w:pPr></w:pPr><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Linux</w:t></w:r></w:p>
+<w:p><w:pPr></w:pPr><w:r><w:t xml:space="preserve">Run it.</w:t></w:r></w:p>`,
+	} {
+		if !strings.Contains(document, expected) {
+			t.Errorf("document.xml has no %s\n%s", expected, document)
+		}
+	}
+	if !strings.Contains(files["word/numbering.xml"], `<w:num w:numId="1"><w:abstractNumId w:val="1"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="3"/></w:lvlOverride></w:num>`) {
+		t.Errorf("ordered list must start from 3\n%s", files["word/numbering.xml"])
+	}
+	if !strings.Contains(files["word/_rels/document.xml.rels"], `Target="https://example.com/a?b=1&amp;c=2" TargetMode="External"`) {
+		t.Errorf("hyperlink relation is missing\n%s", files["word/_rels/document.xml.rels"])
+	}
+	if !strings.Contains(files["word/footnotes.xml"], `The note.`) {
+		t.Errorf("footnote is missing\n%s", files["word/footnotes.xml"])
+	}
+	if files["word/media/image1.png"] != img.String() {
+		t.Errorf("image is not embedded")
+	}
+	if !strings.Contains(files["[Content_Types].xml"], `<Default Extension="png" ContentType="image/png"/>`) {
+		t.Errorf("png content type is missing\n%s", files["[Content_Types].xml"])
+	}
+
+	doc = md2json.MarkdownParse([]byte("See [other](other.md) page.\n"))
+	docx, err = md2json.Docx(&doc, md2json.DocxOptions{ImageDir: dir, Path: "page.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = readDocx(t, docx)
+	if !strings.Contains(files["word/document.xml"], `<w:r><w:t xml:space="preserve">other</w:t></w:r>`) ||
+		strings.Contains(files["word/_rels/document.xml.rels"], "other.md") {
+		t.Errorf("link to page without anchor must be plain text\n%s", files["word/document.xml"])
+	}
+
+	doc = md2json.MarkdownParse([]byte("Note[^1], again[^1] and missing[^2].\n\n[^1]: The note.\n"))
+	docx, err = md2json.Docx(&doc, md2json.DocxOptions{ImageDir: dir, Path: "page.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = readDocx(t, docx)
+	if !strings.Contains(files["word/document.xml"], `<w:footnoteReference w:id="2"/>`) ||
+		!strings.Contains(files["word/document.xml"], `<w:t xml:space="preserve">[2]</w:t>`) ||
+		strings.Contains(files["word/footnotes.xml"], `<w:footnote w:id="3">`) {
+		t.Errorf("repeated note must be copied, missing note must be text\n%s\n%s", files["word/document.xml"], files["word/footnotes.xml"])
+	}
+
+	doc = md2json.MarkdownParse([]byte("Text\n"))
+	doc.Children = append(doc.Children, md2json.Node{Type: "Unknown"})
+	_, err = md2json.Docx(&doc, md2json.DocxOptions{ImageDir: dir, Path: "page.md"})
+	if err == nil || !strings.Contains(err.Error(), "Unknown") {
+		t.Errorf("Docx() = %v, expected unknown kind", err)
+	}
+
+	doc = md2json.MarkdownParse([]byte("Text\n\n![Missing](none.png)\n"))
+	_, err = md2json.Docx(&doc, md2json.DocxOptions{ImageDir: dir, Path: "page.md"})
+	if err == nil || !strings.Contains(err.Error(), "page.md:3:1") {
+		t.Errorf("Docx() = %v, expected missing image", err)
+	}
+}
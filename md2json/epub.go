@@ -0,0 +1,287 @@
+package md2json
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/md5"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+// epubMediaTypes are core media types of images allowed in EPUB 3
+var epubMediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+// epubChapter is file name of the page inside the book
+func epubChapter(page string) string {
+	return strings.TrimSuffix(filepath.ToSlash(filepath.Clean(page)), ".md") + ".xhtml"
+}
+
+// epubLinks points links to pages of the book at their chapters. Links
+// are relative to the root like superlinks make them.
+func epubLinks(n *Node, page string, pages map[string]bool) {
+	if n.Type == Link {
+		href, _ := n.Attributes["href"]
+		if pages[filepath.Clean(href)] {
+			attrs := AttributeMap{}
+			for k, v := range n.Attributes {
+				attrs[k] = v
+			}
+			rel, err := filepath.Rel(filepath.Dir(page), epubChapter(href))
+			if err == nil {
+				attrs["href"] = filepath.ToSlash(rel)
+			}
+			n.Attributes = attrs
+		}
+	}
+	for i := range n.Children {
+		epubLinks(&n.Children[i], page, pages)
+	}
+}
+
+// epubImages collects src of images of the document.
+func epubImages(n *Node, source string, images map[string]string) error {
+	if n.Type == Image {
+		src, _ := n.Attributes["src"]
+		if !strings.Contains(src, "://") {
+			_, ok := epubMediaTypes[strings.ToLower(filepath.Ext(src))]
+			if !ok {
+				return errors.New(fmt.Sprintf("File %s has image %s of format unsupported by EPUB", n.Location(source), src))
+			}
+			if _, ok := images[src]; !ok {
+				images[src] = n.Location(source)
+			}
+		}
+	}
+	for i := range n.Children {
+		err := epubImages(&n.Children[i], source, images)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkXml ensures that text is well-formed XML.
+func checkXml(text []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(text))
+	for {
+		_, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// epubModified is the time of the book for reproducible builds: it is
+// SOURCE_DATE_EPOCH when set, otherwise the time of the newest page.
+func epubModified(inDir string, pages []navItem) time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0)
+	}
+	modified := time.Time{}
+	for _, p := range pages {
+		info, err := os.Stat(filepath.Join(inDir, p.page))
+		if err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	if modified.IsZero() {
+		return time.Now()
+	}
+	return modified
+}
+
+func writeEpubXhtml(title string, lang string, body []byte) []byte {
+	var text bytes.Buffer
+	text.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE html>\n")
+	text.WriteString(fmt.Sprintf("<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" xml:lang=\"%s\" lang=\"%s\">\n", lang, lang))
+	text.WriteString(fmt.Sprintf("<head>\n<meta charset=\"utf-8\" />\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title)))
+	text.Write(body)
+	text.WriteString("</body>\n</html>\n")
+	return text.Bytes()
+}
+
+// writeEpubNav writes toc of nav.xhtml. Sections lead to their first page.
+func writeEpubNav(items []navItem, titles map[string]string) []byte {
+	var text bytes.Buffer
+	text.WriteString("<ol>\n")
+	for _, item := range items {
+		if item.page != "" {
+			text.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(epubChapter(item.page)), html.EscapeString(titles[item.page])))
+			continue
+		}
+		pages := navPages(item.children)
+		if len(pages) == 0 {
+			continue
+		}
+		text.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a>\n", html.EscapeString(epubChapter(pages[0].page)), html.EscapeString(item.title)))
+		text.Write(writeEpubNav(item.children, titles))
+		text.WriteString("</li>\n")
+	}
+	text.WriteString("</ol>\n")
+	return text.Bytes()
+}
+
+// Epub packs pages of the nav of planarized mkdocs config into EPUB 3
+// book. Images are taken from the directory of the config.
+func Epub(mkdocsPath string, output string) error {
+	mkdocs, inDir, items, err := readMkdocsNav(mkdocsPath)
+	if err != nil {
+		return err
+	}
+	imageDir := filepath.Dir(mkdocsPath)
+	title := "Documentation"
+	if name, ok := mkdocs["site_name"]; ok {
+		title = fmt.Sprintf("%v", name)
+	}
+	lang := "en"
+	if theme, ok := mkdocs["theme"].(map[string]interface{}); ok {
+		if l, ok := theme["language"]; ok {
+			lang = fmt.Sprintf("%v", l)
+		}
+	}
+
+	pages := navPages(items)
+	docs, titles, err := readNavPages(inDir, pages)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, p := range pages {
+		known[filepath.Clean(p.page)] = true
+	}
+
+	var book bytes.Buffer
+	w := zip.NewWriter(&book)
+	// mimetype goes first and uncompressed, so readers can detect the book
+	f, err := w.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	f.Write([]byte("application/epub+zip"))
+	f, err = w.Create("META-INF/container.xml")
+	if err != nil {
+		return err
+	}
+	f.Write([]byte(epubContainer))
+
+	var manifest bytes.Buffer
+	var spine bytes.Buffer
+	images := map[string]string{}
+	written := map[string]bool{}
+	for i, p := range pages {
+		chapter := epubChapter(p.page)
+		if written[chapter] {
+			continue
+		}
+		written[chapter] = true
+		doc := docs[p.page]
+		err = epubImages(&doc, doc.Source(filepath.Join(inDir, p.page)), images)
+		if err != nil {
+			return err
+		}
+		epubLinks(&doc, p.page, known)
+		content, _ := htmlContent(&doc)
+		xhtml := writeEpubXhtml(titles[p.page], lang, content)
+		err = checkXml(xhtml)
+		if err != nil {
+			// html passed through from markdown must be well-formed too
+			return errors.New(fmt.Sprintf("Page %s is not valid XHTML: %v", doc.Source(filepath.Join(inDir, p.page)), err))
+		}
+		f, err = w.Create("OEBPS/" + chapter)
+		if err != nil {
+			return err
+		}
+		f.Write(xhtml)
+		manifest.WriteString(fmt.Sprintf("<item id=\"page-%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, html.EscapeString(chapter)))
+		spine.WriteString(fmt.Sprintf("<itemref idref=\"page-%d\"/>\n", i+1))
+	}
+
+	srcs := make([]string, 0, len(images))
+	for src := range images {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	for i, src := range srcs {
+		img, err := os.ReadFile(filepath.Join(imageDir, src))
+		if err != nil {
+			return errors.New(fmt.Sprintf("File %s has invalid link to image %s", images[src], src))
+		}
+		f, err = w.Create("OEBPS/" + filepath.ToSlash(filepath.Clean(src)))
+		if err != nil {
+			return err
+		}
+		f.Write(img)
+		mediaType := epubMediaTypes[strings.ToLower(filepath.Ext(src))]
+		manifest.WriteString(fmt.Sprintf("<item id=\"img-%d\" href=\"%s\" media-type=\"%s\"/>\n", i+1, html.EscapeString(filepath.ToSlash(filepath.Clean(src))), mediaType))
+	}
+
+	f, err = w.Create("OEBPS/nav.xhtml")
+	if err != nil {
+		return err
+	}
+	var nav bytes.Buffer
+	nav.WriteString(fmt.Sprintf("<nav epub:type=\"toc\" id=\"toc\">\n<h1>%s</h1>\n", html.EscapeString(title)))
+	nav.Write(writeEpubNav(items, titles))
+	nav.WriteString("</nav>\n")
+	f.Write(writeEpubXhtml(title, lang, nav.Bytes()))
+
+	// identifier must stay the same for new editions of the book
+	id := md5.Sum([]byte(title))
+	f, err = w.Create("OEBPS/content.opf")
+	if err != nil {
+		return err
+	}
+	var opf bytes.Buffer
+	opf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	opf.WriteString("<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\" unique-identifier=\"bookid\">\n")
+	opf.WriteString("<metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	opf.WriteString(fmt.Sprintf("<dc:identifier id=\"bookid\">urn:uuid:%x-%x-%x-%x-%x</dc:identifier>\n", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]))
+	opf.WriteString(fmt.Sprintf("<dc:title>%s</dc:title>\n", html.EscapeString(title)))
+	opf.WriteString(fmt.Sprintf("<dc:language>%s</dc:language>\n", html.EscapeString(lang)))
+	if author, ok := mkdocs["site_author"]; ok {
+		opf.WriteString(fmt.Sprintf("<dc:creator>%s</dc:creator>\n", html.EscapeString(fmt.Sprintf("%v", author))))
+	}
+	opf.WriteString(fmt.Sprintf("<meta property=\"dcterms:modified\">%s</meta>\n", epubModified(inDir, pages).UTC().Format("2006-01-02T15:04:05Z")))
+	opf.WriteString("</metadata>\n<manifest>\n")
+	opf.WriteString("<item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	opf.Write(manifest.Bytes())
+	opf.WriteString("</manifest>\n<spine>\n")
+	opf.Write(spine.Bytes())
+	opf.WriteString("</spine>\n</package>\n")
+	f.Write(opf.Bytes())
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	return os.WriteFile(output, book.Bytes(), os.ModePerm)
+}
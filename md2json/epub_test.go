@@ -0,0 +1,185 @@
+package md2json_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"marktome/md2json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type epubPackage struct {
+	Manifest []struct {
+		Id         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		Idref string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+	Identifier string `xml:"metadata>identifier"`
+	Title      string `xml:"metadata>title"`
+}
+
+var epubHrefRegexp = regexp.MustCompile(`(?:href|src)="([^"#:]+)(?:#[^"]*)?"`)
+
+// checkEpub validates structure of the book the way epubcheck does:
+// container, package document, manifest, spine and links.
+func checkEpub(t *testing.T, book []byte) map[string][]byte {
+	r, err := zip.NewReader(bytes.NewReader(book), int64(len(book)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for i, f := range r.File {
+		if i == 0 && (f.Name != "mimetype" || f.Method != zip.Store) {
+			t.Errorf("first entry must be stored mimetype, got %s", f.Name)
+		}
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	if string(files["mimetype"]) != "application/epub+zip" {
+		t.Errorf("mimetype is %q", files["mimetype"])
+	}
+	if !strings.Contains(string(files["META-INF/container.xml"]), `full-path="OEBPS/content.opf"`) {
+		t.Errorf("container.xml doesn't point to package\n%s", files["META-INF/container.xml"])
+	}
+	opf := epubPackage{}
+	err = xml.Unmarshal(files["OEBPS/content.opf"], &opf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opf.Identifier == "" || opf.Title == "" {
+		t.Errorf("package has no identifier or title")
+	}
+	ids := map[string]bool{}
+	hasNav := false
+	for _, item := range opf.Manifest {
+		ids[item.Id] = true
+		name := "OEBPS/" + item.Href
+		if _, ok := files[name]; !ok {
+			t.Errorf("manifest item %s is missing", name)
+		}
+		if item.Properties == "nav" {
+			hasNav = true
+		}
+		if item.MediaType != "application/xhtml+xml" {
+			continue
+		}
+		d := xml.NewDecoder(bytes.NewReader(files[name]))
+		// epubcheck rejects repeated ids in the chapter
+		elementIds := map[string]bool{}
+		for {
+			token, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s is not well-formed: %v", name, err)
+				break
+			}
+			start, ok := token.(xml.StartElement)
+			if !ok {
+				continue
+			}
+			for _, attr := range start.Attr {
+				if attr.Name.Local != "id" {
+					continue
+				}
+				if elementIds[attr.Value] {
+					t.Errorf("%s has id %s twice", name, attr.Value)
+				}
+				elementIds[attr.Value] = true
+			}
+		}
+		for _, m := range epubHrefRegexp.FindAllStringSubmatch(string(files[name]), -1) {
+			target := "OEBPS/" + path.Join(path.Dir(item.Href), m[1])
+			if _, ok := files[target]; !ok {
+				t.Errorf("%s links to missing %s", name, target)
+			}
+		}
+	}
+	if !hasNav {
+		t.Errorf("manifest has no nav document")
+	}
+	for _, ref := range opf.Spine {
+		if !ids[ref.Idref] {
+			t.Errorf("spine refers to unknown item %s", ref.Idref)
+		}
+	}
+	return files
+}
+
+func TestEpub(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), os.ModePerm)
+	os.MkdirAll(filepath.Join(dir, "img"), os.ModePerm)
+	os.WriteFile(filepath.Join(dir, "img", "scheme.png"), []byte("png"), os.ModePerm)
+	pages := map[string]string{
+		"intro.md": "# Intro & overview {#intro}\n\nSee [setup](setup.md#setup-run).\n\n![Scheme](img/scheme.png)\n\nLine  \nbreak[^1], again[^1].\n\n---\n\n[^1]: Note.\n",
+		"setup.md": "# Setup {#setup}\n\n## Run {#setup-run}\n\n* [x] done\n\nBack to [intro](intro.md).\n",
+	}
+	for name, text := range pages {
+		doc := md2json.MarkdownParse([]byte(text))
+		md2json.WriteJson(&doc, filepath.Join(dir, "docs", name))
+	}
+	mkdocs := "site_name: Server <docs>\ndocs_dir: docs\nnav:\n  - intro.md\n  - Guide:\n      - setup.md\n"
+	os.WriteFile(filepath.Join(dir, "mkdocs.yml"), []byte(mkdocs), os.ModePerm)
+
+	// the book is as old as its newest page
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	os.Chtimes(filepath.Join(dir, "docs", "intro.md"), time.Unix(1700000000, 0), time.Unix(1700000000, 0))
+	os.Chtimes(filepath.Join(dir, "docs", "setup.md"), time.Unix(1600000000, 0), time.Unix(1600000000, 0))
+
+	output := filepath.Join(dir, "book.epub")
+	err := md2json.Epub(filepath.Join(dir, "mkdocs.yml"), output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	book, _ := os.ReadFile(output)
+	files := checkEpub(t, book)
+	if !strings.Contains(string(files["OEBPS/content.opf"]), `<meta property="dcterms:modified">2023-11-14T22:13:20Z</meta>`) {
+		t.Errorf("modified must be time of the newest page\n%s", files["OEBPS/content.opf"])
+	}
+	if !strings.Contains(string(files["OEBPS/intro.xhtml"]), `<a href="setup.xhtml#setup-run">setup</a>`) {
+		t.Errorf("superlink must lead into the book\n%s", files["OEBPS/intro.xhtml"])
+	}
+	if string(files["OEBPS/img/scheme.png"]) != "png" {
+		t.Errorf("image is not embedded")
+	}
+	if !strings.Contains(string(files["OEBPS/nav.xhtml"]), `<li><a href="setup.xhtml">Guide</a>`) {
+		t.Errorf("nav must have sections\n%s", files["OEBPS/nav.xhtml"])
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1000000000")
+	err = md2json.Epub(filepath.Join(dir, "mkdocs.yml"), output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	book, _ = os.ReadFile(output)
+	files = checkEpub(t, book)
+	if !strings.Contains(string(files["OEBPS/content.opf"]), `<meta property="dcterms:modified">2001-09-09T01:46:40Z</meta>`) {
+		t.Errorf("modified must be SOURCE_DATE_EPOCH\n%s", files["OEBPS/content.opf"])
+	}
+}
+
+func TestEpubInvalidHtml(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), os.ModePerm)
+	doc := md2json.MarkdownParse([]byte("# Page {#page}\n\n<span>a & b</span>\n"))
+	md2json.WriteJson(&doc, filepath.Join(dir, "docs", "page.md"))
+	os.WriteFile(filepath.Join(dir, "mkdocs.yml"), []byte("docs_dir: docs\nnav:\n  - page.md\n"), os.ModePerm)
+	err := md2json.Epub(filepath.Join(dir, "mkdocs.yml"), filepath.Join(dir, "book.epub"))
+	if err == nil || !strings.Contains(err.Error(), "page.md is not valid XHTML") {
+		t.Errorf("Epub() = %v, expected invalid XHTML", err)
+	}
+}
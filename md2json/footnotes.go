@@ -0,0 +1,112 @@
+package md2json
+
+import (
+	"errors"
+	"fmt"
+)
+
+// splitFootnotes returns copy of the document without footnote definitions
+// and the definitions themselves in order of appearance.
+func splitFootnotes(n *Node) (Node, []Node) {
+	defs := []Node{}
+	var split func(n *Node) Node
+	split = func(n *Node) Node {
+		n1 := *n
+		if n.Children != nil {
+			n1.Children = make([]Node, 0, len(n.Children))
+			for i := range n.Children {
+				if n.Children[i].Type == FootnoteDef {
+					defs = append(defs, n.Children[i])
+					continue
+				}
+				n1.Children = append(n1.Children, split(&n.Children[i]))
+			}
+		}
+		return n1
+	}
+	doc := split(n)
+	return doc, defs
+}
+
+// attachFootnotes puts content of definitions into children of references,
+// so writers that render footnotes in place don't need to look them up.
+func attachFootnotes(n *Node, defs []Node) {
+	byId := map[string]*Node{}
+	for i := range defs {
+		byId[defs[i].Attributes["id"]] = &defs[i]
+	}
+	var attach func(n *Node)
+	attach = func(n *Node) {
+		if n.Type == FootnoteRef {
+			def, ok := byId[n.Attributes["id"]]
+			if ok {
+				n.Children = def.Children
+			}
+			return
+		}
+		for i := range n.Children {
+			attach(&n.Children[i])
+		}
+	}
+	attach(n)
+}
+
+// CheckFootnotes ensures that every footnote reference has a definition
+// on the same page and every definition is referenced.
+func CheckFootnotes(doc *Node, path string) error {
+	_, defs := splitFootnotes(doc)
+	declared := map[string]*Node{}
+	for i := range defs {
+		id := defs[i].Attributes["id"]
+		old, ok := declared[id]
+		if ok {
+			return errors.New(fmt.Sprintf("Footnote %s double declared in %s and %s", id, defs[i].Location(path), old.Location(path)))
+		}
+		declared[id] = &defs[i]
+	}
+
+	used := map[string]bool{}
+	var checkRefs func(n *Node) error
+	checkRefs = func(n *Node) error {
+		if n.Type == FootnoteRef {
+			id := n.Attributes["id"]
+			_, ok := declared[id]
+			if !ok {
+				return errors.New(fmt.Sprintf("Footnote %s in file %s is not defined", id, n.Location(path)))
+			}
+			used[id] = true
+		}
+		for i := range n.Children {
+			err := checkRefs(&n.Children[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := checkRefs(doc)
+	if err != nil {
+		return err
+	}
+	for i := range defs {
+		id := defs[i].Attributes["id"]
+		if !used[id] {
+			return errors.New(fmt.Sprintf("Footnote %s in file %s is never used", id, defs[i].Location(path)))
+		}
+	}
+	return nil
+}
+
+func Footnotes(rootDir string) error {
+	for _, fp := range ListAllMd(rootDir) {
+		doc, err := ReadJson(fp)
+		if err != nil {
+			return err
+		}
+		err = CheckFootnotes(&doc, doc.Source(fp))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
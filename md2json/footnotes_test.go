@@ -0,0 +1,28 @@
+package md2json_test
+
+import (
+	"marktome/md2json"
+	"strings"
+	"testing"
+)
+
+func TestCheckFootnotes(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"Text[^1].\n\n[^1]: Note.\n", ""},
+		{"Text[^1] and[^2].\n\n[^1]: Note.\n", "Footnote 2 in file page.md:1:13 is not defined"},
+		{"Text.\n\n[^1]: Note.\n", "Footnote 1 in file page.md:3:1 is never used"},
+	}
+	for _, tt := range tests {
+		doc := md2json.MarkdownParse([]byte(tt.input))
+		err := md2json.CheckFootnotes(&doc, "page.md")
+		if tt.err == "" && err != nil {
+			t.Errorf("CheckFootnotes(%q) unexpected error %v", tt.input, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("CheckFootnotes(%q) = %v, expected %s", tt.input, err, tt.err)
+		}
+	}
+}
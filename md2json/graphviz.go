@@ -11,7 +11,9 @@ import (
 	"strings"
 )
 
-func replaceGraphviz(imageDir string, n *Node, path string, cacheDir string) (bool, error) {
+// replaceGraphviz renders graphviz tags of the document stored in path.
+// Errors refer to source, the markdown file the document was parsed from.
+func replaceGraphviz(imageDir string, n *Node, path string, source string, cacheDir string) (bool, error) {
 	dirty := false
 	if n.Type == HTML && n.Attributes != nil {
 		tag, ok1 := n.Attributes["tag"]
@@ -31,13 +33,13 @@ func replaceGraphviz(imageDir string, n *Node, path string, cacheDir string) (bo
 				cmd := exec.Command("/bin/bash", "-c", command)
 				_, err := cmd.Output()
 				if err != nil {
-					return false, err
+					return false, errors.New(fmt.Sprintf("Failed to run dot for graph in %s: %v", n.Location(source), err))
 				}
 			} else {
 				fmt.Printf("Skip generating %s\n", imagePath)
 			}
 			if _, err := os.Stat(cachePath); err != nil {
-				return false, errors.New(fmt.Sprintf("Failed to create graph from %s %s", path, graphPath))
+				return false, errors.New(fmt.Sprintf("Failed to create graph from %s %s", n.Location(source), graphPath))
 			}
 			img, err := os.ReadFile(cachePath)
 			if err != nil {
@@ -69,7 +71,7 @@ func replaceGraphviz(imageDir string, n *Node, path string, cacheDir string) (bo
 	}
 	if n.Children != nil {
 		for i := range n.Children {
-			d, err := replaceGraphviz(imageDir, &n.Children[i], path, cacheDir)
+			d, err := replaceGraphviz(imageDir, &n.Children[i], path, source, cacheDir)
 			if err != nil {
 				return false, err
 			}
@@ -86,7 +88,7 @@ func Graphviz(rootDir string, imageDir string, cacheDir string) error {
 		if err != nil {
 			return err
 		}
-		dirty, err = replaceGraphviz(imageDir, &doc, fp, cacheDir)
+		dirty, err = replaceGraphviz(imageDir, &doc, fp, doc.Source(fp), cacheDir)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,493 @@
+package md2json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HtmlPageOptions tune HTML produced from the document.
+type HtmlPageOptions struct {
+	// Template is html/template page with .Title, .Content and .Meta.
+	// Without template only the content is written.
+	Template *template.Template
+}
+
+// htmlPage is what the page template gets
+type htmlPage struct {
+	Title   string
+	Content template.HTML
+	Meta    AttributeMap
+}
+
+func Html(n *Node) ([]byte, error) {
+	return HtmlOptions(n, HtmlPageOptions{})
+}
+
+func HtmlOptions(n *Node, opts HtmlPageOptions) ([]byte, error) {
+	text, doc := htmlContent(n)
+	if opts.Template == nil {
+		return text, nil
+	}
+	title, _, _ := doc.Heading()
+	var page bytes.Buffer
+	err := opts.Template.Execute(&page, htmlPage{
+		Title:   title,
+		Content: template.HTML(text),
+		Meta:    n.Attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page.Bytes(), nil
+}
+
+// htmlContent renders the document and returns it with the copy of the
+// document where every heading has id.
+func htmlContent(n *Node) ([]byte, Node) {
+	doc, footnotes := splitFootnotes(n)
+	prepareHtmlHeadings(&doc)
+	prepareHtmlFootnotes(&doc)
+	var text bytes.Buffer
+	text.Write(writeHtmlChildren(&doc))
+	text.Write(writeHtmlFootnotes(footnotes))
+	return text.Bytes(), doc
+}
+
+// Json2Html writes document as html page, links to other documents
+// lead to their html pages.
+func Json2Html(input string, output string, opts HtmlPageOptions) error {
+	doc, err := ReadJson(input)
+	if err != nil {
+		return err
+	}
+	text, err := HtmlOptions(&doc, opts)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(output, text, os.ModePerm)
+	return err
+}
+
+// ReadHtmlTemplate reads page template for HtmlPageOptions
+func ReadHtmlTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid html template %s: %v", path, err))
+	}
+	return tmpl, nil
+}
+
+var htmlIdRegexp = regexp.MustCompile(`[^\p{L}\p{N}_ -]+`)
+
+// htmlId makes heading id from its title the way mkdocs toc does.
+func htmlId(title string) string {
+	id := htmlIdRegexp.ReplaceAllString(strings.ToLower(title), "")
+	return strings.Join(strings.Fields(id), "-")
+}
+
+// prepareHtmlFootnotes numbers repeated references to the same footnote,
+// so their ids get suffix like -2 and stay unique. Back link of the
+// footnote leads to the first reference.
+func prepareHtmlFootnotes(n *Node) {
+	count := map[string]int{}
+	var prepare func(n *Node)
+	prepare = func(n *Node) {
+		if n.Type == FootnoteRef {
+			id := n.Attributes["id"]
+			count[id]++
+			if count[id] > 1 {
+				n.Attributes = AttributeMap{"id": id, "repeat": strconv.Itoa(count[id])}
+			}
+		}
+		for i := range n.Children {
+			prepare(&n.Children[i])
+		}
+	}
+	prepare(n)
+}
+
+// prepareHtmlHeadings stores id in every heading of the document copy.
+// Headings without id get one from the title, repeated ids get suffix
+// like _1, so ids do not change while the document is not edited.
+func prepareHtmlHeadings(n *Node) {
+	used := map[string]bool{}
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		if n.Type == Heading && n.Attributes["id"] != "" {
+			used[n.Attributes["id"]] = true
+		}
+		for i := range n.Children {
+			collect(&n.Children[i])
+		}
+	}
+	collect(n)
+	var prepare func(n *Node)
+	prepare = func(n *Node) {
+		if n.Type == Heading {
+			attrs := AttributeMap{}
+			for k, v := range n.Attributes {
+				attrs[k] = v
+			}
+			if attrs["id"] == "" {
+				id := htmlId(n.Literal)
+				unique := id
+				for i := 1; used[unique] || unique == ""; i++ {
+					unique = fmt.Sprintf("%s_%d", id, i)
+				}
+				used[unique] = true
+				attrs["id"] = unique
+			}
+			n.Attributes = attrs
+		}
+		for i := range n.Children {
+			prepare(&n.Children[i])
+		}
+	}
+	prepare(n)
+}
+
+// writeHtmlChildren writes children of n, blocks end with new line.
+func writeHtmlChildren(n *Node) []byte {
+	var text bytes.Buffer
+	for _, ch := range n.Children {
+		text.Write(writeHtmlNode(&ch))
+	}
+	return text.Bytes()
+}
+
+func writeHtmlNode(n *Node) []byte {
+	switch n.Type {
+	case Paragraph:
+		return []byte("<p>" + string(writeHtmlChildren(n)) + "</p>\n")
+	case Text:
+		return []byte(html.EscapeString(n.Literal))
+	case Comment:
+		return []byte{}
+	case Image:
+		return writeHtmlImage(n)
+	case Link:
+		return writeHtmlLink(n)
+	case Emphasis:
+		return writeHtmlInliner(n, "em")
+	case Bold:
+		return writeHtmlInliner(n, "strong")
+	case Strikethrough:
+		return writeHtmlInliner(n, "del")
+	case Code:
+		return []byte("<code>" + html.EscapeString(n.Literal) + "</code>")
+	case Heading:
+		return writeHtmlHeading(n)
+	case List:
+		return writeHtmlList(n)
+	case ListItem:
+		return writeHtmlListItem(n)
+	case Admonition:
+		return writeHtmlAdmonition(n)
+	case Tabs:
+		return writeHtmlTabs(n)
+	case CodeFence:
+		return writeHtmlCodeFence(n)
+	case HTML:
+		return writeHtmlTag(n)
+	case Table:
+		return writeHtmlTable(n)
+	case Blockquote:
+		return []byte("<blockquote>\n" + string(writeHtmlChildren(n)) + "</blockquote>\n")
+	case FootnoteRef:
+		id := html.EscapeString(n.Attributes["id"])
+		ref := id
+		if repeat, ok := n.Attributes["repeat"]; ok {
+			ref += "-" + repeat
+		}
+		return []byte(fmt.Sprintf(`<sup id="fnref-%s"><a href="#fn-%s">%s</a></sup>`, ref, id, id))
+	case ThematicBreak:
+		return []byte("<hr />\n")
+	case LineBreak:
+		return []byte("<br />\n")
+	case "NewPage":
+		return []byte{}
+	default:
+		fmt.Println("Type", n.Type)
+	}
+	return []byte{}
+}
+
+// writeHtmlInliner writes content of emphasis-like node, which is either
+// plain literal or nested inline nodes.
+func writeHtmlInliner(n *Node, tag string) []byte {
+	inner := []byte(html.EscapeString(n.Literal))
+	if n.Children != nil {
+		inner = writeHtmlChildren(n)
+	}
+	return []byte("<" + tag + ">" + string(inner) + "</" + tag + ">")
+}
+
+func writeHtmlHeading(n *Node) []byte {
+	level, err := strconv.Atoi(n.Attributes["level"])
+	if err != nil {
+		level = 3
+	}
+	if level < 1 {
+		level = 1
+	}
+	if level > 6 {
+		level = 6
+	}
+	return []byte(fmt.Sprintf("<h%d id=\"%s\">%s</h%d>\n", level, html.EscapeString(n.Attributes["id"]), html.EscapeString(n.Literal), level))
+}
+
+// htmlUrl points links to other documents at their html pages.
+func htmlUrl(n *Node) string {
+	src, _ := n.Attributes["href"]
+	if !strings.Contains(src, "://") && strings.HasSuffix(src, ".md") {
+		src = strings.TrimSuffix(src, ".md") + ".html"
+	}
+	anchor, ok := n.Attributes["anchor"]
+	if ok {
+		return src + "#" + anchor
+	}
+	return src
+}
+
+func writeHtmlLink(n *Node) []byte {
+	var text bytes.Buffer
+	text.WriteString(fmt.Sprintf(`<a href="%s"`, html.EscapeString(htmlUrl(n))))
+	title, ok := n.Attributes["title"]
+	if ok {
+		text.WriteString(fmt.Sprintf(` title="%s"`, html.EscapeString(title)))
+	}
+	text.WriteString(">")
+	if _, ok := n.Attributes["autolink"]; ok {
+		text.WriteString(html.EscapeString(n.Attributes["href"]))
+	} else {
+		text.WriteString(html.EscapeString(n.Literal))
+	}
+	text.WriteString("</a>")
+	return text.Bytes()
+}
+
+func writeHtmlImage(n *Node) []byte {
+	return []byte(fmt.Sprintf(`<img src="%s" alt="%s" />`, html.EscapeString(n.Attributes["src"]), html.EscapeString(n.Literal)))
+}
+
+func writeHtmlList(n *Node) []byte {
+	var text bytes.Buffer
+	tag := "ul"
+	if _, ordered := n.Attributes["ordered"]; ordered {
+		tag = "ol"
+	}
+	text.WriteString("<" + tag)
+	start, err := strconv.Atoi(n.Attributes["start"])
+	if tag == "ol" && err == nil {
+		text.WriteString(fmt.Sprintf(` start="%d"`, start))
+	}
+	text.WriteString(">\n")
+	text.Write(writeHtmlChildren(n))
+	text.WriteString("</" + tag + ">\n")
+	return text.Bytes()
+}
+
+// writeHtmlListItem writes item with single paragraph inline, like tight
+// markdown lists are rendered.
+func writeHtmlListItem(n *Node) []byte {
+	var text bytes.Buffer
+	text.WriteString("<li>")
+	switch n.Attributes["checked"] {
+	case "true":
+		text.WriteString(`<input type="checkbox" disabled="disabled" checked="checked" /> `)
+	case "false":
+		text.WriteString(`<input type="checkbox" disabled="disabled" /> `)
+	}
+	if len(n.Children) == 1 && n.Children[0].Type == Paragraph {
+		text.Write(writeHtmlChildren(&n.Children[0]))
+	} else {
+		text.WriteString("\n")
+		text.Write(writeHtmlChildren(n))
+	}
+	text.WriteString("</li>\n")
+	return text.Bytes()
+}
+
+// writeHtmlAdmonition writes div like mkdocs-material does, collapsible
+// admonitions are details titled by summary.
+func writeHtmlAdmonition(n *Node) []byte {
+	var text bytes.Buffer
+	level := html.EscapeString(n.Attributes["level"])
+	title := html.EscapeString(n.AdmonitionTitle())
+	switch n.Attributes["collapsible"] {
+	case "closed", "open":
+		open := ""
+		if n.Attributes["collapsible"] == "open" {
+			open = " open=\"open\""
+		}
+		text.WriteString(fmt.Sprintf("<details class=\"%s\"%s>\n", level, open))
+		text.WriteString(fmt.Sprintf("<summary>%s</summary>\n", title))
+		text.Write(writeHtmlChildren(n))
+		text.WriteString("</details>\n")
+		return text.Bytes()
+	}
+	text.WriteString(fmt.Sprintf("<div class=\"admonition %s\">\n", level))
+	if title != "" {
+		text.WriteString(fmt.Sprintf("<p class=\"admonition-title\">%s</p>\n", title))
+	}
+	text.Write(writeHtmlChildren(n))
+	text.WriteString("</div>\n")
+	return text.Bytes()
+}
+
+// writeHtmlTabs writes every tab as div titled like admonition, all tabs
+// are shown one after another.
+func writeHtmlTabs(n *Node) []byte {
+	var text bytes.Buffer
+	text.WriteString("<div class=\"tabbed-set\">\n")
+	for _, tab := range n.Children {
+		text.WriteString("<div class=\"tabbed\">\n")
+		text.WriteString(fmt.Sprintf("<p class=\"tabbed-title\">%s</p>\n", html.EscapeString(tab.Attributes["title"])))
+		text.Write(writeHtmlChildren(&tab))
+		text.WriteString("</div>\n")
+	}
+	text.WriteString("</div>\n")
+	return text.Bytes()
+}
+
+func writeHtmlCodeFence(n *Node) []byte {
+	var text bytes.Buffer
+	title, hasTitle := n.Attributes["title"]
+	if hasTitle {
+		text.WriteString("<figure class=\"code\">\n")
+		text.WriteString(fmt.Sprintf("<figcaption>%s</figcaption>\n", html.EscapeString(title)))
+	}
+	text.WriteString("<pre")
+	for _, k := range codeFenceOptions {
+		v, ok := n.Attributes[k]
+		if ok && k != "title" {
+			text.WriteString(fmt.Sprintf(` data-%s="%s"`, strings.ReplaceAll(k, "_", "-"), html.EscapeString(v)))
+		}
+	}
+	text.WriteString("><code")
+	lang := strings.SplitN(n.Attributes["lang"], " ", 2)[0]
+	if lang != "" {
+		text.WriteString(fmt.Sprintf(` class="language-%s"`, html.EscapeString(lang)))
+	}
+	text.WriteString(">")
+	text.WriteString(html.EscapeString(n.Literal))
+	text.WriteString("</code></pre>\n")
+	if hasTitle {
+		text.WriteString("</figure>\n")
+	}
+	return text.Bytes()
+}
+
+// writeHtmlTag passes html of the document through. Tags known to
+// preprocessors are written the way they are meant to be seen.
+func writeHtmlTag(n *Node) []byte {
+	var text bytes.Buffer
+	tag, _ := n.Attributes["tag"]
+	switch tag {
+	case "if":
+		return writeHtmlChildren(n)
+	case "br":
+		return []byte("<br />")
+	case "link":
+		link := Node{Type: Link, Literal: n.Literal, Attributes: n.Attributes}
+		return writeHtmlLink(&link)
+	case "graphviz":
+		return []byte("<pre class=\"graphviz\">" + html.EscapeString(n.Literal) + "</pre>\n")
+	}
+	keys := make([]string, 0, len(n.Attributes))
+	for k := range n.Attributes {
+		if k != "tag" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	text.WriteString("<" + tag)
+	for _, k := range keys {
+		text.WriteString(fmt.Sprintf(` %s="%s"`, k, html.EscapeString(n.Attributes[k])))
+	}
+	if htmlVoidElements[tag] {
+		text.WriteString(" />")
+		return text.Bytes()
+	}
+	text.WriteString(">")
+	if n.Children != nil {
+		text.Write(writeHtmlChildren(n))
+	} else {
+		text.WriteString(n.Literal)
+	}
+	text.WriteString("</" + tag + ">")
+	return text.Bytes()
+}
+
+func writeHtmlTable(n *Node) []byte {
+	var text bytes.Buffer
+	header := n.Children[0]
+	body := n.Children[1]
+	aligns := []string{}
+	text.WriteString("<table>\n<thead>\n<tr>")
+	for _, th := range header.Children {
+		align := th.Attributes["align"]
+		aligns = append(aligns, align)
+		text.WriteString(htmlCellTag("th", align))
+		if th.Type == Text {
+			text.WriteString(html.EscapeString(th.Literal))
+		} else {
+			text.Write(writeHtmlChildren(&th))
+		}
+		text.WriteString("</th>")
+	}
+	text.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, row := range body.Children {
+		text.WriteString("<tr>")
+		for i, cell := range row.Children {
+			align := ""
+			if i < len(aligns) {
+				align = aligns[i]
+			}
+			text.WriteString(htmlCellTag("td", align))
+			text.Write(writeHtmlChildren(&cell))
+			text.WriteString("</td>")
+		}
+		text.WriteString("</tr>\n")
+	}
+	text.WriteString("</tbody>\n</table>\n")
+	return text.Bytes()
+}
+
+func htmlCellTag(tag string, align string) string {
+	if align == "" {
+		return "<" + tag + ">"
+	}
+	return fmt.Sprintf(`<%s style="text-align: %s">`, tag, align)
+}
+
+// writeHtmlFootnotes writes definitions as list after the document.
+func writeHtmlFootnotes(defs []Node) []byte {
+	if len(defs) == 0 {
+		return []byte{}
+	}
+	var text bytes.Buffer
+	text.WriteString("<section class=\"footnotes\">\n<ol>\n")
+	for _, def := range defs {
+		id := html.EscapeString(def.Attributes["id"])
+		text.WriteString(fmt.Sprintf("<li id=\"fn-%s\">\n", id))
+		text.Write(writeHtmlChildren(&def))
+		text.WriteString(fmt.Sprintf("<a href=\"#fnref-%s\">&#8617;</a>\n</li>\n", id))
+	}
+	text.WriteString("</ol>\n</section>\n")
+	return text.Bytes()
+}
+
+// htmlOutput is path of html page made from json document
+func htmlOutput(outDir string, rel string) string {
+	return filepath.Join(outDir, strings.TrimSuffix(rel, ".md")+".html")
+}
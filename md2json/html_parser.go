@@ -0,0 +1,694 @@
+package md2json
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// htmlElement is element or text of parsed html. Text has no name,
+// comments are named "!--".
+type htmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	text     string
+	children []*htmlElement
+	line     int
+	column   int
+}
+
+func (e *htmlElement) attr(name string) (string, bool) {
+	for _, a := range e.attrs {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func (e *htmlElement) hasClass(class string) bool {
+	classes, _ := e.attr("class")
+	for _, c := range strings.Fields(classes) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// textContent is text of the element and its descendants.
+func (e *htmlElement) textContent() string {
+	if e.name == "" {
+		return e.text
+	}
+	var text strings.Builder
+	for _, ch := range e.children {
+		if ch.name != "!--" {
+			text.WriteString(ch.textContent())
+		}
+	}
+	return text.String()
+}
+
+// htmlVoidElements have no content and no end tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+var htmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+var htmlAttrEscaper = strings.NewReplacer("&", "&amp;", "\"", "&quot;")
+
+// outerHtml writes the element back as html.
+func (e *htmlElement) outerHtml() string {
+	switch e.name {
+	case "":
+		return htmlTextEscaper.Replace(e.text)
+	case "!--":
+		return "<!--" + e.text + "-->"
+	}
+	var text strings.Builder
+	text.WriteString("<" + e.name)
+	for _, a := range e.attrs {
+		text.WriteString(fmt.Sprintf(` %s="%s"`, a.Name.Local, htmlAttrEscaper.Replace(a.Value)))
+	}
+	if htmlVoidElements[e.name] {
+		text.WriteString(" />")
+		return text.String()
+	}
+	text.WriteString(">" + e.innerHtml() + "</" + e.name + ">")
+	return text.String()
+}
+
+func (e *htmlElement) innerHtml() string {
+	var text strings.Builder
+	for _, ch := range e.children {
+		text.WriteString(ch.outerHtml())
+	}
+	return text.String()
+}
+
+// htmlImpliedEnd lists elements closed by start of the element, unless
+// one of the boundaries is open between them.
+type htmlImpliedEnd struct {
+	closes     []string
+	boundaries []string
+}
+
+var htmlImpliedEnds = map[string]htmlImpliedEnd{
+	"li":    {[]string{"li"}, []string{"ul", "ol"}},
+	"dt":    {[]string{"dt", "dd"}, []string{"dl"}},
+	"dd":    {[]string{"dt", "dd"}, []string{"dl"}},
+	"tr":    {[]string{"tr"}, []string{"table", "thead", "tbody", "tfoot"}},
+	"td":    {[]string{"td", "th"}, []string{"tr", "table"}},
+	"th":    {[]string{"td", "th"}, []string{"tr", "table"}},
+	"thead": {[]string{"thead", "tbody"}, []string{"table"}},
+	"tbody": {[]string{"thead", "tbody"}, []string{"table"}},
+	"tfoot": {[]string{"thead", "tbody"}, []string{"table"}},
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+var htmlScriptRegexp = regexp.MustCompile(`(?is)<script\b.*?</script\s*>`)
+var htmlStyleRegexp = regexp.MustCompile(`(?is)<style\b.*?</style\s*>`)
+
+// dropHtmlScripts removes scripts and styles, their content is not html
+// and breaks the tokenizer. Line breaks stay for positions of the rest.
+func dropHtmlScripts(source []byte) []byte {
+	blank := func(s []byte) []byte {
+		return bytes.Repeat([]byte{'\n'}, bytes.Count(s, []byte{'\n'}))
+	}
+	source = htmlScriptRegexp.ReplaceAllFunc(source, blank)
+	return htmlStyleRegexp.ReplaceAllFunc(source, blank)
+}
+
+// parseHtmlTree reads html leniently: missing end tags are invented, void
+// elements need no end tag and named entities are known. End tags close
+// the nearest open element of the name, stray ones are skipped, elements
+// open at the end of the file end there.
+func parseHtmlTree(source []byte) (*htmlElement, error) {
+	d := xml.NewDecoder(bytes.NewReader(dropHtmlScripts(source)))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	root := &htmlElement{name: "#document"}
+	// open elements, raw tokens of the decoder are not matched, end tags
+	// implied by html are known here only
+	open := []*htmlElement{root}
+	closeTo := func(e *htmlElement) {
+		for i := len(open) - 1; i > 0; i-- {
+			if open[i] == e {
+				open = open[:i]
+				return
+			}
+		}
+	}
+	for {
+		line, column := d.InputPos()
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &htmlElement{name: strings.ToLower(t.Name.Local), attrs: t.Attr, line: line, column: column}
+			if implied, ok := htmlImpliedEnds[e.name]; ok {
+				for i := len(open) - 1; i > 0 && !containsName(implied.boundaries, open[i].name); i-- {
+					if containsName(implied.closes, open[i].name) {
+						closeTo(open[i])
+						break
+					}
+				}
+			}
+			if htmlBlockElements[e.name] {
+				// paragraph ends with start of block
+				i := len(open) - 1
+				for i > 0 && !htmlBlockElements[open[i].name] {
+					i--
+				}
+				if open[i].name == "p" {
+					closeTo(open[i])
+				}
+			}
+			parent := open[len(open)-1]
+			parent.children = append(parent.children, e)
+			if !htmlVoidElements[e.name] {
+				open = append(open, e)
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			for i := len(open) - 1; i > 0; i-- {
+				if open[i].name == name {
+					closeTo(open[i])
+					break
+				}
+			}
+		case xml.CharData:
+			parent := open[len(open)-1]
+			parent.children = append(parent.children, &htmlElement{text: string(t), line: line, column: column})
+		case xml.Comment:
+			parent := open[len(open)-1]
+			parent.children = append(parent.children, &htmlElement{name: "!--", text: string(t), line: line, column: column})
+		}
+	}
+	return root, nil
+}
+
+// htmlBlockElements are elements that can't be inside paragraph
+var htmlBlockElements = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"p": true, "ul": true, "ol": true, "pre": true, "table": true, "blockquote": true,
+	"hr": true, "aside": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "footer": true, "nav": true, "figure": true, "body": true, "html": true,
+	"details": true, "dl": true, "form": true,
+}
+
+// htmlContainers are elements whose content goes in place of them
+var htmlContainers = map[string]bool{
+	"html": true, "body": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "footer": true, "figure": true,
+}
+
+var htmlSpacesRegexp = regexp.MustCompile(`\s+`)
+var htmlAlignRegexp = regexp.MustCompile(`text-align:\s*(left|right|center)`)
+var htmlLangRegexp = regexp.MustCompile(`(?:^|\s)(?:language|lang)-(\S+)`)
+
+// HtmlParse converts html document to the document. Elements that have
+// no kind are kept as HTML nodes, like the markdown parser does.
+func HtmlParse(source []byte) (Node, error) {
+	root, err := parseHtmlTree(source)
+	if err != nil {
+		return Node{}, err
+	}
+	doc := Node{
+		Type:     Document,
+		Children: htmlBlocks(root.children),
+	}
+	return doc, nil
+}
+
+func Html2Json(input string, output string) error {
+	source, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	doc, err := HtmlParse(source)
+	if err != nil {
+		return errors.New(fmt.Sprintf("File %s: %v", input, err))
+	}
+	return WriteJson(&doc, output)
+}
+
+// Html2JsonDir converts html pages of the directory to documents with .md
+// extension.
+func Html2JsonDir(rootDir string, outDir string) error {
+	return filepath.WalkDir(rootDir, func(fp string, fi os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || !(strings.HasSuffix(fp, ".html") || strings.HasSuffix(fp, ".htm")) {
+			return nil
+		}
+		rel, _ := filepath.Rel(rootDir, fp)
+		output := filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+".md")
+		os.MkdirAll(filepath.Dir(output), os.ModePerm)
+		return Html2Json(fp, output)
+	})
+}
+
+func htmlPosition(e *htmlElement) *Position {
+	if e.line == 0 {
+		return nil
+	}
+	p := Point{Line: e.line, Column: e.column}
+	return &Position{Start: p, End: p}
+}
+
+// htmlBlocks converts elements to blocks, inline content between blocks
+// becomes paragraphs.
+func htmlBlocks(elements []*htmlElement) []Node {
+	nodes := []Node{}
+	inlines := []*htmlElement{}
+	flush := func() {
+		children := htmlInlines(inlines)
+		if len(children) > 0 {
+			nodes = append(nodes, Node{Type: Paragraph, Children: children, Position: htmlPosition(inlines[0])})
+		}
+		inlines = []*htmlElement{}
+	}
+	for _, e := range elements {
+		if e.name == "" || (!htmlBlockElements[e.name] && e.name != "!--" && e.name != "head") {
+			inlines = append(inlines, e)
+			continue
+		}
+		flush()
+		nodes = append(nodes, htmlBlock(e)...)
+	}
+	flush()
+	return nodes
+}
+
+func htmlBlock(e *htmlElement) []Node {
+	pos := htmlPosition(e)
+	switch e.name {
+	case "head":
+		return nil
+	case "!--":
+		return []Node{{Type: Comment, Literal: e.text, Position: pos}}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		n := Node{Type: Heading, Literal: htmlText(e.textContent()), Attributes: AttributeMap{"level": e.name[1:]}, Position: pos}
+		if id, ok := e.attr("id"); ok {
+			n.Attributes["id"] = id
+		}
+		return []Node{n}
+	case "p":
+		children := htmlInlines(e.children)
+		if len(children) == 0 {
+			return nil
+		}
+		return []Node{{Type: Paragraph, Children: children, Position: pos}}
+	case "ul", "ol":
+		return []Node{htmlList(e)}
+	case "pre":
+		return []Node{htmlCodeFence(e, "")}
+	case "table":
+		return []Node{htmlTable(e)}
+	case "blockquote":
+		return []Node{{Type: Blockquote, Children: htmlBlocks(e.children), Position: pos}}
+	case "hr":
+		return []Node{{Type: ThematicBreak, Position: pos}}
+	case "aside":
+		return []Node{htmlAdmonition(e)}
+	case "div":
+		if e.hasClass("admonition") {
+			return []Node{htmlAdmonition(e)}
+		}
+	case "details":
+		if _, ok := e.attr("class"); ok {
+			return []Node{htmlAdmonition(e)}
+		}
+	case "figure":
+		// code with title, the way json2html writes it
+		for _, ch := range e.children {
+			if ch.name == "pre" {
+				title := ""
+				for _, c := range e.children {
+					if c.name == "figcaption" {
+						title = htmlText(c.textContent())
+					}
+				}
+				return []Node{htmlCodeFence(ch, title)}
+			}
+		}
+	case "section":
+		if e.hasClass("footnotes") {
+			return htmlFootnotes(e)
+		}
+	}
+	if htmlContainers[e.name] {
+		return htmlBlocks(e.children)
+	}
+	return []Node{htmlUnknown(e)}
+}
+
+// htmlUnknown keeps element as HTML node with tag and attributes.
+func htmlUnknown(e *htmlElement) Node {
+	attrs := AttributeMap{"tag": e.name}
+	for _, a := range e.attrs {
+		attrs[a.Name.Local] = a.Value
+	}
+	return Node{Type: HTML, Attributes: attrs, Literal: e.innerHtml(), Position: htmlPosition(e)}
+}
+
+// htmlText collapses spaces like browsers do.
+func htmlText(text string) string {
+	return strings.TrimSpace(htmlSpacesRegexp.ReplaceAllString(text, " "))
+}
+
+// htmlInlines converts inline content, spaces at the edges are dropped.
+func htmlInlines(elements []*htmlElement) []Node {
+	nodes := []Node{}
+	for _, e := range elements {
+		for _, n := range htmlInline(e) {
+			last := len(nodes) - 1
+			if n.Type == Text && last >= 0 && nodes[last].Type == Text {
+				nodes[last].Literal += n.Literal
+				continue
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	// spaces before and after line breaks and at the edges are not seen
+	for i := range nodes {
+		if nodes[i].Type != Text {
+			continue
+		}
+		if i == 0 || nodes[i-1].Type == LineBreak {
+			nodes[i].Literal = strings.TrimLeft(nodes[i].Literal, " ")
+		}
+		if i == len(nodes)-1 || nodes[i+1].Type == LineBreak {
+			nodes[i].Literal = strings.TrimRight(nodes[i].Literal, " ")
+		}
+	}
+	result := []Node{}
+	for _, n := range nodes {
+		if n.Type != Text || n.Literal != "" {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// htmlInliner makes emphasis-like node, plain text stays in its literal
+// like the markdown parser does.
+func htmlInliner(kind Kind, e *htmlElement) Node {
+	children := htmlInlines(e.children)
+	if len(children) == 1 && children[0].Type == Text {
+		return Node{Type: kind, Literal: children[0].Literal, Position: htmlPosition(e)}
+	}
+	return Node{Type: kind, Children: children, Position: htmlPosition(e)}
+}
+
+// htmlHref makes links to html pages of the site links to their markdown.
+func htmlHref(href string) string {
+	if strings.Contains(href, "://") || strings.HasPrefix(href, "mailto:") {
+		return href
+	}
+	page, anchor, hasAnchor := strings.Cut(href, "#")
+	if strings.HasSuffix(page, ".html") {
+		page = strings.TrimSuffix(page, ".html") + ".md"
+	}
+	if hasAnchor {
+		return page + "#" + anchor
+	}
+	return page
+}
+
+func htmlInline(e *htmlElement) []Node {
+	pos := htmlPosition(e)
+	switch e.name {
+	case "":
+		return []Node{{Type: Text, Literal: htmlSpacesRegexp.ReplaceAllString(e.text, " ")}}
+	case "!--":
+		return []Node{{Type: Comment, Literal: e.text, Position: pos}}
+	case "strong", "b":
+		return []Node{htmlInliner(Bold, e)}
+	case "em", "i":
+		return []Node{htmlInliner(Emphasis, e)}
+	case "del", "s", "strike":
+		return []Node{htmlInliner(Strikethrough, e)}
+	case "code", "kbd", "tt":
+		return []Node{{Type: Code, Literal: e.textContent(), Position: pos}}
+	case "br":
+		return []Node{{Type: LineBreak, Position: pos}}
+	case "img":
+		src, _ := e.attr("src")
+		alt, _ := e.attr("alt")
+		return []Node{{Type: Image, Literal: alt, Attributes: AttributeMap{"src": src}, Position: pos}}
+	case "a":
+		href, ok := e.attr("href")
+		if !ok {
+			// anchors without href only mark places
+			return htmlInlines(e.children)
+		}
+		text := htmlText(e.textContent())
+		n := Node{Type: Link, Literal: text, Attributes: AttributeMap{}, Position: pos}
+		setLinkUrl(&n, htmlHref(href))
+		if title, ok := e.attr("title"); ok {
+			n.Attributes["title"] = title
+		}
+		if text == href && strings.Contains(href, "://") {
+			n.Attributes["autolink"] = "angle"
+		}
+		return []Node{n}
+	case "sup":
+		// footnote reference the way json2html writes it
+		if len(e.children) == 1 && e.children[0].name == "a" {
+			href, _ := e.children[0].attr("href")
+			if strings.HasPrefix(href, "#fn-") {
+				return []Node{{Type: FootnoteRef, Attributes: AttributeMap{"id": strings.TrimPrefix(href, "#fn-")}, Position: pos}}
+			}
+		}
+	case "span":
+		if len(e.attrs) == 0 {
+			return htmlInlines(e.children)
+		}
+	}
+	return []Node{htmlUnknown(e)}
+}
+
+func htmlList(e *htmlElement) Node {
+	n := Node{Type: List, Children: []Node{}, Position: htmlPosition(e)}
+	if e.name == "ol" {
+		n.Attributes = AttributeMap{"ordered": "true"}
+		if start, ok := e.attr("start"); ok && start != "1" {
+			n.Attributes["start"] = start
+		}
+	}
+	for _, li := range e.children {
+		if li.name != "li" {
+			continue
+		}
+		item := Node{Type: ListItem, Position: htmlPosition(li)}
+		content := li.children
+		// checkbox at the start makes task item
+		for i, ch := range li.children {
+			if kind, _ := ch.attr("type"); ch.name == "input" && kind == "checkbox" {
+				_, checked := ch.attr("checked")
+				item.Attributes = AttributeMap{"checked": fmt.Sprintf("%v", checked)}
+				content = li.children[i+1:]
+			}
+			if ch.name != "" || strings.TrimSpace(ch.text) != "" {
+				break
+			}
+		}
+		item.Children = htmlBlocks(content)
+		n.Children = append(n.Children, item)
+	}
+	return n
+}
+
+// htmlCodeFence takes language from class of code like language-go.
+func htmlCodeFence(pre *htmlElement, title string) Node {
+	n := Node{Type: CodeFence, Literal: pre.textContent(), Position: htmlPosition(pre)}
+	attrs := AttributeMap{}
+	for _, ch := range pre.children {
+		if ch.name == "code" {
+			class, _ := ch.attr("class")
+			if m := htmlLangRegexp.FindStringSubmatch(class); m != nil {
+				attrs["lang"] = m[1]
+			}
+		}
+	}
+	for _, k := range codeFenceOptions {
+		if v, ok := pre.attr("data-" + strings.ReplaceAll(k, "_", "-")); ok {
+			attrs[k] = v
+		}
+	}
+	if title != "" {
+		attrs["title"] = title
+	}
+	if len(attrs) > 0 {
+		n.Attributes = attrs
+	}
+	if !strings.HasSuffix(n.Literal, "\n") {
+		n.Literal += "\n"
+	}
+	return n
+}
+
+// htmlAdmonition takes level from classes and title from the title
+// paragraph, details are collapsible admonitions titled by summary.
+func htmlAdmonition(e *htmlElement) Node {
+	level := "note"
+	classes, _ := e.attr("class")
+	for _, c := range strings.Fields(classes) {
+		if c != "admonition" {
+			level = c
+			break
+		}
+	}
+	n := Node{Type: Admonition, Attributes: AttributeMap{"level": level}, Position: htmlPosition(e)}
+	if e.name == "details" {
+		n.Attributes["collapsible"] = "closed"
+		if _, ok := e.attr("open"); ok {
+			n.Attributes["collapsible"] = "open"
+		}
+	}
+	content := []*htmlElement{}
+	for _, ch := range e.children {
+		if ch.hasClass("admonition-title") || ch.name == "summary" {
+			// title that is just the level is not kept
+			if title := htmlText(ch.textContent()); title != n.AdmonitionTitle() {
+				n.Attributes["title"] = title
+			}
+			continue
+		}
+		content = append(content, ch)
+	}
+	n.Children = htmlBlocks(content)
+	return n
+}
+
+// htmlRows finds rows of the table section by section.
+func htmlRows(e *htmlElement) ([]*htmlElement, []*htmlElement) {
+	head := []*htmlElement{}
+	body := []*htmlElement{}
+	for _, ch := range e.children {
+		switch ch.name {
+		case "thead":
+			for _, tr := range ch.children {
+				if tr.name == "tr" {
+					head = append(head, tr)
+				}
+			}
+		case "tbody", "tfoot":
+			for _, tr := range ch.children {
+				if tr.name == "tr" {
+					body = append(body, tr)
+				}
+			}
+		case "tr":
+			body = append(body, ch)
+		}
+	}
+	return head, body
+}
+
+func htmlCells(tr *htmlElement) []Node {
+	cells := []Node{}
+	for _, td := range tr.children {
+		if td.name != "td" && td.name != "th" {
+			continue
+		}
+		cell := Node{Type: TableCell, Children: htmlInlines(td.children)}
+		style, _ := td.attr("style")
+		align, _ := td.attr("align")
+		if m := htmlAlignRegexp.FindStringSubmatch(style); m != nil {
+			align = m[1]
+		}
+		if align != "" {
+			cell.Attributes = AttributeMap{"align": align}
+		}
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+// htmlTable converts table, markdown tables always have header, so the
+// first row is the header when table has no thead.
+func htmlTable(e *htmlElement) Node {
+	head, body := htmlRows(e)
+	if len(head) == 0 && len(body) > 0 {
+		head, body = body[:1], body[1:]
+	}
+	thead := Node{Type: TableHead, Children: []Node{}}
+	if len(head) > 0 {
+		thead.Children = htmlCells(head[0])
+	}
+	// alignment of columns is kept in header cells
+	aligns := make([]string, len(thead.Children))
+	tbody := Node{Type: TableBody, Children: []Node{}}
+	for _, tr := range body {
+		cells := htmlCells(tr)
+		for i := range cells {
+			if i < len(aligns) && aligns[i] == "" && cells[i].Attributes != nil {
+				aligns[i] = cells[i].Attributes["align"]
+			}
+			cells[i].Attributes = nil
+		}
+		tbody.Children = append(tbody.Children, Node{Type: TableRow, Children: cells})
+	}
+	for i, align := range aligns {
+		if align != "" && thead.Children[i].Attributes == nil {
+			thead.Children[i].Attributes = AttributeMap{"align": align}
+		}
+	}
+	return Node{Type: Table, Children: []Node{thead, tbody}, Position: htmlPosition(e)}
+}
+
+// htmlFootnotes converts footnotes the way json2html writes them.
+func htmlFootnotes(e *htmlElement) []Node {
+	defs := []Node{}
+	for _, ol := range e.children {
+		if ol.name != "ol" {
+			continue
+		}
+		for _, li := range ol.children {
+			id, ok := li.attr("id")
+			if li.name != "li" || !ok {
+				continue
+			}
+			content := []*htmlElement{}
+			for _, ch := range li.children {
+				// back reference leads to the text
+				if href, _ := ch.attr("href"); ch.name == "a" && strings.HasPrefix(href, "#fnref-") {
+					continue
+				}
+				content = append(content, ch)
+			}
+			defs = append(defs, Node{
+				Type:       FootnoteDef,
+				Attributes: AttributeMap{"id": strings.TrimPrefix(id, "fn-")},
+				Children:   htmlBlocks(content),
+				Position:   htmlPosition(li),
+			})
+		}
+	}
+	return defs
+}
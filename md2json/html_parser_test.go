@@ -0,0 +1,65 @@
+package md2json_test
+
+import (
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHtmlParse(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/htmlimport/*.html")
+	for _, fp := range paths {
+		name := strings.TrimSuffix(fp, ".html")
+		t.Run(name, func(t *testing.T) {
+			input, _ := os.ReadFile(fp)
+			expected, _ := os.ReadFile(name + ".md")
+			doc, err := md2json.HtmlParse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := md2json.WriteDocument(&doc)
+			if string(text) != string(expected) {
+				t.Errorf("HtmlParse()\nactual\n%s\nexpected\n%s", text, expected)
+			}
+		})
+	}
+}
+
+func TestHtmlPositions(t *testing.T) {
+	source := "<h1 id=\"title\">Title</h1>\n<p>First paragraph\ncontinues.</p>\n<pre>code</pre>\n"
+	doc, err := md2json.HtmlParse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"page.html:1:1", "page.html:2:1", "page.html:4:1"}
+	for i, n := range doc.Children {
+		if location := n.Location("page.html"); location != expected[i] {
+			t.Errorf("%s at %s, expected %s", n.Type, location, expected[i])
+		}
+	}
+}
+
+func TestHtmlParseUnclosed(t *testing.T) {
+	// elements open at the end and stray end tags are fine in html
+	tests := map[string]string{
+		"<p>x":                         "x\n",
+		"<p>a<p>b</p>":                 "a\n\nb\n",
+		"<html><body><p>x</p>":         "x\n",
+		"a</br>b":                      "ab\n",
+		"</p></p>":                     "",
+		"<p>a <b>bold</span> text</p>": "a **bold text**\n",
+	}
+	for source, expected := range tests {
+		doc, err := md2json.HtmlParse([]byte(source))
+		if err != nil {
+			t.Errorf("HtmlParse(%q) error %v", source, err)
+			continue
+		}
+		doc.Attributes = nil
+		if text := md2json.WriteDocument(&doc); string(text) != expected {
+			t.Errorf("HtmlParse(%q)\nactual\n%s\nexpected\n%s", source, text, expected)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package md2json_test
+
+import (
+	"bytes"
+	"html/template"
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHtml(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/html/*.md")
+	for _, fp := range paths {
+		name := strings.TrimSuffix(fp, ".md")
+		t.Run(name, func(t *testing.T) {
+			input, _ := os.ReadFile(fp)
+			expected, _ := os.ReadFile(name + ".html")
+			doc := md2json.MarkdownParse(input)
+			text, err := md2json.Html(&doc)
+			if err != nil {
+				t.Error(err)
+			}
+			if !bytes.Equal(text, expected) {
+				t.Errorf("Html()\nactual\n%s\nexpected\n%s", text, expected)
+			}
+		})
+	}
+}
+
+func TestHtmlTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse("<title>{{.Title}}</title>\n<main>{{.Content}}</main>\n"))
+	doc := md2json.MarkdownParse([]byte("# Q&A\n\nText.\n"))
+	text, err := md2json.HtmlOptions(&doc, md2json.HtmlPageOptions{Template: tmpl})
+	if err != nil {
+		t.Error(err)
+	}
+	expected := "<title>Q&amp;A</title>\n<main><h1 id=\"qa\">Q&amp;A</h1>\n<p>Text.</p>\n</main>\n"
+	if string(text) != expected {
+		t.Errorf("HtmlOptions()\nactual\n%s\nexpected\n%s", text, expected)
+	}
+}
@@ -3,11 +3,50 @@ package md2json
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+// TexOptions tune LaTeX produced from the document.
+type TexOptions struct {
+	// Highlight is package for code fences: listings (default) or minted
+	Highlight string
+	// Languages override highlighter names of fence languages
+	Languages map[string]string
+	// Svg is how svg images without pdf copy are written: skipped (default)
+	// or included with svg package
+	Svg string
+	// Path of the document for warnings
+	Path string
+}
+
 func Latex(n *Node) ([]byte, error) {
+	return LatexOptions(n, TexOptions{})
+}
+
+func LatexOptions(n *Node, opts TexOptions) ([]byte, error) {
+	doc, footnotes := splitFootnotes(n)
+	attachFootnotes(&doc, footnotes)
+	prepareTexFootnotes(&doc)
+	prepareTexCode(&doc, opts)
+	skipped := prepareTexImages(&doc, opts)
+	if len(skipped) > 0 {
+		source := n.Source(opts.Path)
+		if source == "" {
+			source = "document"
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %d images are not included in %s:\n", len(skipped), source)
+		for _, img := range skipped {
+			fmt.Fprintf(os.Stderr, "  %s %s\n", img.Location(source), img.Attributes["src"])
+		}
+	}
+	return writeTexBlocks(&doc), nil
+}
+
+// writeTexBlocks writes block children of n separated by empty lines.
+func writeTexBlocks(n *Node) []byte {
 	var text bytes.Buffer
 	if n.Children != nil {
 		for _, ch := range n.Children {
@@ -15,7 +54,7 @@ func Latex(n *Node) ([]byte, error) {
 			text.WriteString("\n")
 		}
 	}
-	return text.Bytes(), nil
+	return text.Bytes()
 }
 
 func writeTexNode(n *Node) []byte {
@@ -32,6 +71,8 @@ func writeTexNode(n *Node) []byte {
 		return writeTexEmphasis(n)
 	case Bold:
 		return writeTexBold(n)
+	case Strikethrough:
+		return writeTexStrikethrough(n)
 	case Code:
 		return writeTexCode(n)
 	case Heading:
@@ -46,8 +87,20 @@ func writeTexNode(n *Node) []byte {
 		return writeTexCodeFence(n)
 	case Table:
 		return writeTexTable(n)
+	case Blockquote:
+		return writeTexBlockquote(n)
+	case FootnoteRef:
+		return writeTexFootnote(n)
+	case Tabs:
+		return writeTexTabs(n)
+	case ThematicBreak:
+		return []byte("\\hrule\n")
+	case LineBreak:
+		return []byte("\\\\\n")
 	case "NewPage":
 		return writeTexNewpage(n)
+	case Comment:
+		return []byte{}
 	// case HTML:
 	// 	return writeHTML(n)
 	default:
@@ -88,12 +141,52 @@ func writeTexText(n *Node) []byte {
 	return []byte(escapeTexText(n.Literal))
 }
 
+// texImageFormats can be included by pdflatex
+var texImageFormats = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".pdf": true, ".eps": true}
+
+// prepareTexImages stores in images of the document copy the file to
+// include and the way to include it. svg images use pdf made by svg2pdf
+// or svg package. Images that can't be included are returned.
+func prepareTexImages(n *Node, opts TexOptions) []*Node {
+	skipped := []*Node{}
+	if n.Type == Image {
+		attrs := AttributeMap{}
+		for k, v := range n.Attributes {
+			attrs[k] = v
+		}
+		src, _ := attrs["src"]
+		ext := strings.ToLower(filepath.Ext(src))
+		pdf, hasPdf := attrs["pdf"]
+		if strings.Contains(src, "://") {
+			skipped = append(skipped, n)
+		} else if ext == ".svg" && hasPdf {
+			attrs["texsrc"] = pdf
+		} else if ext == ".svg" && opts.Svg == "svg" {
+			attrs["texsrc"] = src
+			attrs["texsvg"] = "true"
+		} else if texImageFormats[ext] {
+			attrs["texsrc"] = src
+		} else {
+			skipped = append(skipped, n)
+		}
+		n.Attributes = attrs
+	}
+	for i := range n.Children {
+		skipped = append(skipped, prepareTexImages(&n.Children[i], opts)...)
+	}
+	return skipped
+}
+
 func writeTexImage(n *Node) []byte {
-	src, _ := n.Attributes["src"]
-	// TODO: add svg support
-	if strings.HasSuffix(src, ".svg") {
+	src, ok := n.Attributes["texsrc"]
+	if !ok {
 		return []byte{}
 	}
+	if _, ok := n.Attributes["texsvg"]; ok {
+		return []byte(fmt.Sprintf(
+			"\\includesvg[width=\\linewidth]{%s}\n",
+			strings.TrimSuffix(src, filepath.Ext(src))))
+	}
 	return []byte(fmt.Sprintf(
 		"\\documentImage{%s}{%s}\n",
 		escapeTexText(n.Literal),
@@ -103,23 +196,37 @@ func writeTexImage(n *Node) []byte {
 func writeTexLink(n *Node) []byte {
 	src, _ := n.Attributes["href"]
 	anchor, hasAnchor := n.Attributes["anchor"]
+	if _, ok := n.Attributes["autolink"]; ok {
+		return []byte(fmt.Sprintf(`\url{%s}`, escapeTexUrl(src)))
+	}
 	if strings.HasPrefix(src, "http") || !hasAnchor || len(anchor) == 0 {
-		return []byte(fmt.Sprintf(`\href{%s}{%s}`, src, escapeTexText(n.Literal)))
+		return []byte(fmt.Sprintf(`\href{%s}{%s}`, escapeTexUrl(linkUrl(n)), escapeTexText(n.Literal)))
 	} else {
-		return []byte(fmt.Sprintf(`\hyperref[%s]{%s}`, escapeTexText(labelTex(anchor)), escapeTexText(n.Literal)))
+		return []byte(fmt.Sprintf(`\hyperref[%s]{%s}`, labelTex(anchor), escapeTexText(n.Literal)))
+	}
+}
+
+// writeTexInliner writes content of emphasis-like node, which is either
+// plain literal or nested inline nodes.
+func writeTexInliner(n *Node) []byte {
+	if n.Children == nil {
+		return []byte(escapeTexText(n.Literal))
 	}
+	return writeTexChildren(n)
 }
 
 func writeTexEmphasis(n *Node) []byte {
-	return []byte(fmt.Sprintf(`\emph{%s}`, escapeTexText(n.Literal)))
+	return []byte(fmt.Sprintf(`\emph{%s}`, writeTexInliner(n)))
 }
 
+// writeTexBold switches font in group rather than \textbf argument,
+// \inlineCode like \verb does not work in arguments.
 func writeTexBold(n *Node) []byte {
-	return []byte(fmt.Sprintf(`\textbf{%s}`, escapeTexText(n.Literal)))
+	return []byte(fmt.Sprintf(`{\bfseries %s}`, writeTexInliner(n)))
 }
 
-func labelTex(t string) string {
-	return strings.ReplaceAll(t, "_", "-")
+func writeTexStrikethrough(n *Node) []byte {
+	return []byte(fmt.Sprintf(`\sout{%s}`, writeTexInliner(n)))
 }
 
 func writeTexHeading(n *Node) []byte {
@@ -137,6 +244,8 @@ func writeTexHeading(n *Node) []byte {
 		level = 3
 	}
 	switch level {
+	case -1:
+		text.WriteString("part")
 	case 0:
 		text.WriteString("chapter")
 	case 1:
@@ -165,63 +274,81 @@ func writeTexHeading(n *Node) []byte {
 
 func writeTexList(n *Node) []byte {
 	var text bytes.Buffer
-	text.WriteString("\\begin{itemize}\n")
+	env := "itemize"
+	_, ordered := n.Attributes["ordered"]
+	if ordered {
+		env = "enumerate"
+	}
+	text.WriteString("\\begin{" + env + "}\n")
+	start, err := strconv.Atoi(n.Attributes["start"])
+	if ordered && err == nil {
+		// @enumctr is the counter of current nesting depth: enumi, enumii, ...
+		text.WriteString(fmt.Sprintf("\\setcounter{\\csname @enumctr\\endcsname}{%d}\n", start-1))
+	}
 	text.Write(writeTexChildren(n))
-	text.WriteString("\\end{itemize}\n\n")
+	text.WriteString("\\end{" + env + "}\n\n")
 	return text.Bytes()
 }
 
 func writeTexListItem(n *Node) []byte {
 	var text bytes.Buffer
-	text.WriteString("\\item\n")
-	// inner := writeTexChildren(n)
-	inner := writeTexNode(&n.Children[0])
-	rows := bytes.Split(inner, []byte{'\n'})
-	if len(rows[len(rows)-1]) == 0 {
-		rows = rows[:len(rows)-1]
-	}
-	for _, r := range rows {
-		text.WriteString("  ")
-		text.Write(r)
-		text.WriteByte('\n')
+	switch n.Attributes["checked"] {
+	case "true":
+		text.WriteString("\\item[$\\boxtimes$]\n")
+	case "false":
+		text.WriteString("\\item[$\\square$]\n")
+	default:
+		text.WriteString("\\item\n")
 	}
+	inner := bytes.TrimRight(writeTexBlocks(n), "\n")
+	text.Write(indentTex(inner, "  "))
+	text.WriteByte('\n')
 	return text.Bytes()
 }
 
-func escapeTexText(t string) string {
-	t = strings.ReplaceAll(t, "#", "\\#")
-	t = strings.ReplaceAll(t, "%", "\\%")
-	t = strings.ReplaceAll(t, "$", "\\$")
-	t = strings.ReplaceAll(t, "&", "\\&")
-	t = strings.ReplaceAll(t, "_", "\\_")
-	return t
-}
+// texVerbatimEnvironments keep their content as is, so it must not be indented
+var texVerbatimEnvironments = []string{"lstlisting", "minted"}
 
-func escapeTex(in string) string {
-	t := strings.ReplaceAll(in, "|", "\\|")
-	t = strings.ReplaceAll(t, "_", "\\_")
-	t = strings.ReplaceAll(t, "#", "\\#")
-	return t
+// indentTex shifts every non-empty line right except content of verbatim
+// environments.
+func indentTex(inner []byte, prefix string) []byte {
+	var text bytes.Buffer
+	verbatim := ""
+	for i, r := range bytes.Split(inner, []byte{'\n'}) {
+		if i > 0 {
+			text.WriteByte('\n')
+		}
+		if verbatim != "" {
+			text.Write(r)
+			if bytes.HasPrefix(r, []byte("\\end{"+verbatim+"}")) {
+				verbatim = ""
+			}
+			continue
+		}
+		if len(r) > 0 {
+			text.WriteString(prefix)
+			text.Write(r)
+		}
+		for _, env := range texVerbatimEnvironments {
+			if bytes.HasPrefix(bytes.TrimLeft(r, " "), []byte("\\begin{"+env+"}")) {
+				verbatim = env
+			}
+		}
+	}
+	return text.Bytes()
 }
 
 func writeTexCode(n *Node) []byte {
 	var text bytes.Buffer
-	// lang := "c"
-	// if n.Attributes != nil {
-	// 	lang1, ok := n.Attributes["lang"]
-	// 	if !ok {
-	// 		lang = lang1
-	// 	}
-	// }
-	text.WriteString("\\inlineCode")
-	bracket := "|"
-	if strings.Index(n.Literal, "|") < 0 {
-		bracket = "|"
-	} else if strings.Index(n.Literal, "$") < 0 {
-		bracket = "$"
-	} else if strings.Index(n.Literal, "^") < 0 {
-		bracket = "^"
+	bracket, ok := texCodeDelimiter(n.Literal)
+	if _, argument := n.Attributes["argument"]; !ok || argument {
+		// \inlineCode works like \verb and needs a symbol absent in code
+		text.WriteString("\\texttt{")
+		text.WriteString(escapeTexText(n.Literal))
+		text.WriteString("}")
+		return text.Bytes()
 	}
+	text.WriteString("\\inlineCode")
 	text.WriteString(bracket)
 	text.WriteString(n.Literal)
 	text.WriteString(bracket)
@@ -229,46 +356,150 @@ func writeTexCode(n *Node) []byte {
 }
 
 func writeTexCodeFence(n *Node) []byte {
-	var text bytes.Buffer
-	text.WriteString("\\begin{multilineCode}\n")
-	text.WriteString(n.Literal)
-	text.WriteString("\\end{multilineCode}\n")
-	return text.Bytes()
+	if n.Attributes["highlight"] == "minted" {
+		return writeTexMinted(n)
+	}
+	return writeTexListing(n)
 }
 
+// writeTexAdmonition writes environment named by level, the title goes
+// as optional argument when it is set. Empty title is no title.
 func writeTexAdmonition(n *Node) []byte {
 	var text bytes.Buffer
 	level, _ := n.Attributes["level"]
 	text.WriteString("\\begin{")
 	text.WriteString(level)
-	text.WriteString("}\n")
-	text.Write(writeTexChildren(n))
+	text.WriteString("}")
+	if title := n.Attributes["title"]; title != "" {
+		text.WriteString("[")
+		text.WriteString(escapeTexText(title))
+		text.WriteString("]")
+	}
+	text.WriteString("\n")
+	text.Write(bytes.TrimRight(writeTexBlocks(n), "\n"))
 	text.WriteString("\n\\end{")
 	text.WriteString(level)
 	text.WriteString("}\n\n")
 	return text.Bytes()
 }
 
+// writeTexTabs writes tabs one after another, every tab is unnumbered
+// paragraph with the title and framed box of the framed package.
+func writeTexTabs(n *Node) []byte {
+	var text bytes.Buffer
+	for _, tab := range n.Children {
+		text.WriteString("\\paragraph*{")
+		text.WriteString(escapeTexText(tab.Attributes["title"]))
+		text.WriteString("}\n\\begin{framed}\n")
+		text.Write(bytes.TrimRight(writeTexBlocks(&tab), "\n"))
+		text.WriteString("\n\\end{framed}\n\n")
+	}
+	return text.Bytes()
+}
+
+// prepareTexFootnotes makes content of footnotes safe for argument of
+// \footnote and marks repeated references with the number of footnotes
+// written after the first one, so they refer to its number.
+func prepareTexFootnotes(n *Node) {
+	written := map[string]int{}
+	var prepare func(n *Node)
+	prepare = func(n *Node) {
+		if n.Type == FootnoteRef {
+			id := n.Attributes["id"]
+			first, ok := written[id]
+			if ok {
+				n.Attributes = AttributeMap{"id": id, "repeat": strconv.Itoa(len(written) - first - 1)}
+				n.Children = nil
+				return
+			}
+			written[id] = len(written)
+			n.Children = texArguments(n.Children)
+		}
+		for i := range n.Children {
+			prepare(&n.Children[i])
+		}
+	}
+	prepare(n)
+}
+
+// texArguments copies nodes with inline code marked to be written as
+// \texttt, \inlineCode like \verb does not work in arguments.
+func texArguments(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	copied := make([]Node, len(nodes))
+	for i, n := range nodes {
+		if n.Type == Code {
+			n.Attributes = AttributeMap{"argument": "true"}
+		}
+		n.Children = texArguments(n.Children)
+		copied[i] = n
+	}
+	return copied
+}
+
+func writeTexFootnote(n *Node) []byte {
+	if repeat, ok := n.Attributes["repeat"]; ok {
+		// mark of the footnote written before counts back from the last
+		if repeat == "0" {
+			return []byte("\\footnotemark[\\value{footnote}]")
+		}
+		return []byte(fmt.Sprintf("\\footnotemark[\\numexpr\\value{footnote}-%s\\relax]", repeat))
+	}
+	var text bytes.Buffer
+	text.WriteString("\\footnote{")
+	text.Write(bytes.TrimSpace(writeTexBlocks(n)))
+	text.WriteString("}")
+	return text.Bytes()
+}
+
+func writeTexBlockquote(n *Node) []byte {
+	var text bytes.Buffer
+	text.WriteString("\\begin{quote}\n")
+	text.Write(bytes.TrimRight(writeTexBlocks(n), "\n"))
+	text.WriteString("\n\\end{quote}\n\n")
+	return text.Bytes()
+}
+
+// writeTexTable writes longtable, which breaks across pages and repeats
+// header on every page. Columns share the line width equally unless the
+// table has widths hint like "3,1,2" with relative column widths.
 func writeTexTable(n *Node) []byte {
 	var text bytes.Buffer
 	header := n.Children[0]
 	body := n.Children[1]
-	text.WriteString("\\begin{tabular}{")
+	widths := texColumnWidths(n, len(header.Children))
+	text.WriteString("\\begin{longtable}{")
 	var h bytes.Buffer
 	for i, th := range header.Children {
 		if i > 0 {
 			text.WriteString("|")
 			h.WriteString(" & ")
 		}
-		text.WriteString("c")
-		h.WriteString("\\textbf{")
-		h.WriteString(escapeTexText(th.Literal))
+		switch th.Attributes["align"] {
+		case "center":
+			text.WriteString(">{\\centering\\arraybackslash}")
+		case "right":
+			text.WriteString(">{\\raggedleft\\arraybackslash}")
+		default:
+			text.WriteString(">{\\raggedright\\arraybackslash}")
+		}
+		text.WriteString(fmt.Sprintf("p{\\dimexpr %.3f\\linewidth-2\\tabcolsep\\relax}", widths[i]))
+		h.WriteString("{\\bfseries ")
+		if th.Type == Text {
+			h.WriteString(escapeTexText(th.Literal))
+		} else {
+			h.Write(writeTexChildren(&th))
+		}
 		h.WriteString("}")
 	}
 	text.WriteString("}\n")
 	h.WriteString("\\\\\n")
 	text.Write(h.Bytes())
-	text.WriteString("\\hline\n")
+	text.WriteString("\\hline\n\\endfirsthead\n")
+	text.Write(h.Bytes())
+	text.WriteString("\\hline\n\\endhead\n")
 	for _, row := range body.Children {
 		for i, cell := range row.Children {
 			if i > 0 {
@@ -278,6 +509,31 @@ func writeTexTable(n *Node) []byte {
 		}
 		text.WriteString(" \\\\\n")
 	}
-	text.WriteString("\\end{tabular}\n\n")
+	text.WriteString("\\end{longtable}\n\n")
 	return text.Bytes()
 }
+
+// texColumnWidths returns share of line width for every column.
+func texColumnWidths(n *Node, columns int) []float64 {
+	widths := make([]float64, columns)
+	for i := range widths {
+		widths[i] = 1
+	}
+	hint, ok := n.Attributes["widths"]
+	if ok {
+		for i, w := range strings.Split(hint, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+			if i < columns && err == nil && v > 0 {
+				widths[i] = v
+			}
+		}
+	}
+	total := 0.0
+	for _, w := range widths {
+		total += w
+	}
+	for i := range widths {
+		widths[i] = widths[i] / total
+	}
+	return widths
+}
@@ -0,0 +1,141 @@
+package md2json
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// listingsLanguages maps fence languages to names known by listings.
+// Languages not listed here are written without highlighting, because
+// listings fails on unknown language.
+var listingsLanguages = map[string]string{
+	"bash":     "bash",
+	"sh":       "bash",
+	"shell":    "bash",
+	"console":  "bash",
+	"python":   "Python",
+	"py":       "Python",
+	"c":        "C",
+	"cpp":      "C++",
+	"c++":      "C++",
+	"java":     "Java",
+	"erlang":   "erlang",
+	"erl":      "erlang",
+	"sql":      "SQL",
+	"xml":      "XML",
+	"html":     "HTML",
+	"ruby":     "Ruby",
+	"perl":     "Perl",
+	"php":      "PHP",
+	"lua":      "Lua",
+	"make":     "make",
+	"makefile": "make",
+	"tex":      "TeX",
+	"latex":    "TeX",
+}
+
+// prepareTexCode stores highlighter and its name of the language in code
+// fences of the document copy, so writeTexCodeFence doesn't need options.
+// minted uses pygments like mkdocs does, so fence languages are passed as is.
+// minted cannot escape its terminator, such code goes to listings.
+func prepareTexCode(n *Node, opts TexOptions) {
+	if n.Type == CodeFence {
+		attrs := AttributeMap{}
+		for k, v := range n.Attributes {
+			attrs[k] = v
+		}
+		highlight := opts.Highlight
+		if highlight == "" || strings.Contains(n.Literal, "\\end{minted}") {
+			highlight = "listings"
+		}
+		attrs["highlight"] = highlight
+		lang := strings.ToLower(strings.SplitN(attrs["lang"], " ", 2)[0])
+		texlang, ok := opts.Languages[lang]
+		if !ok && highlight == "minted" {
+			texlang = lang
+		} else if !ok {
+			texlang = listingsLanguages[lang]
+		}
+		if texlang != "" {
+			attrs["texlang"] = texlang
+		}
+		n.Attributes = attrs
+	}
+	for i := range n.Children {
+		prepareTexCode(&n.Children[i], opts)
+	}
+}
+
+// texListingTerminator breaks \end{lstlisting} inside code with empty
+// escape to LaTeX, so listings doesn't stop there.
+const texListingTerminator = "\\end{lstlisting(*@@*)}"
+
+// writeTexListing writes code fence as listings environment. Highlighted
+// lines are not supported by listings and are ignored.
+func writeTexListing(n *Node) []byte {
+	var text bytes.Buffer
+	options := []string{}
+	lang, ok := n.Attributes["texlang"]
+	if ok {
+		options = append(options, "language="+lang)
+	}
+	title, ok := n.Attributes["title"]
+	if ok {
+		options = append(options, "title={"+escapeTexText(title)+"}")
+	}
+	linenums, ok := n.Attributes["linenums"]
+	if ok {
+		options = append(options, "numbers=left", "firstnumber="+linenums)
+	}
+	code := n.Literal
+	if strings.Contains(code, "\\end{lstlisting}") {
+		options = append(options, "escapeinside={(*@}{@*)}")
+		code = strings.ReplaceAll(code, "\\end{lstlisting}", texListingTerminator)
+	}
+	text.WriteString("\\begin{lstlisting}")
+	if len(options) > 0 {
+		text.WriteString("[" + strings.Join(options, ",") + "]")
+	}
+	text.WriteString("\n")
+	text.WriteString(code)
+	if !strings.HasSuffix(code, "\n") {
+		text.WriteString("\n")
+	}
+	text.WriteString("\\end{lstlisting}\n")
+	return text.Bytes()
+}
+
+// writeTexMinted writes code fence as minted environment. minted has no
+// title, so it is written as a bold line above the code.
+func writeTexMinted(n *Node) []byte {
+	var text bytes.Buffer
+	title, ok := n.Attributes["title"]
+	if ok {
+		text.WriteString(fmt.Sprintf("\\noindent\\textbf{%s}\\par\n", escapeTexText(title)))
+	}
+	options := []string{}
+	linenums, ok := n.Attributes["linenums"]
+	if ok {
+		options = append(options, "linenos", "firstnumber="+linenums)
+	}
+	hlLines, ok := n.Attributes["hl_lines"]
+	if ok {
+		options = append(options, "highlightlines={"+strings.Join(strings.Fields(hlLines), ",")+"}")
+	}
+	lang, ok := n.Attributes["texlang"]
+	if !ok {
+		lang = "text"
+	}
+	text.WriteString("\\begin{minted}")
+	if len(options) > 0 {
+		text.WriteString("[" + strings.Join(options, ",") + "]")
+	}
+	text.WriteString("{" + lang + "}\n")
+	text.WriteString(n.Literal)
+	if !strings.HasSuffix(n.Literal, "\n") {
+		text.WriteString("\n")
+	}
+	text.WriteString("\\end{minted}\n")
+	return text.Bytes()
+}
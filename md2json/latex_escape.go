@@ -0,0 +1,77 @@
+package md2json
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// texTextReplacer escapes body text. It works in one pass, so backslash
+// produced for one symbol is not escaped again.
+var texTextReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`#`, `\#`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+	`<`, `\textless{}`,
+	`>`, `\textgreater{}`,
+	`|`, `\textbar{}`,
+	" ", `~`,
+)
+
+func escapeTexText(t string) string {
+	t = texTextReplacer.Replace(t)
+	// -- and --- are ligatures for dashes, but in docs they are options
+	// like --help. Twice, because replacements do not overlap.
+	t = strings.ReplaceAll(t, "--", "-{}-")
+	t = strings.ReplaceAll(t, "--", "-{}-")
+	return t
+}
+
+// escapeTexUrl prepares url for \href and \url. Symbols that break
+// hyperref are percent-encoded, # and % are escaped because links may
+// appear in arguments of other commands like \footnote.
+func escapeTexUrl(url string) string {
+	var text strings.Builder
+	for _, c := range url {
+		switch c {
+		case '\\', '{', '}', ' ', '~', '^', '|', '<', '>', '"':
+			text.WriteString(fmt.Sprintf(`\%%%02X`, c))
+		case '#', '%':
+			text.WriteByte('\\')
+			text.WriteRune(c)
+		default:
+			text.WriteRune(c)
+		}
+	}
+	return text.String()
+}
+
+// labelTex makes \label and \hyperref key from heading id. Everything
+// except letters, digits and safe punctuation becomes dash.
+func labelTex(t string) string {
+	return strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("-.:/", c) {
+			return c
+		}
+		return '-'
+	}, t)
+}
+
+// texCodeDelimiters are tried in order for \inlineCode|code|
+var texCodeDelimiters = []string{"|", "$", "^", "!", "+", "=", "@", "/", ";", "'", "\""}
+
+func texCodeDelimiter(code string) (string, bool) {
+	for _, d := range texCodeDelimiters {
+		if !strings.Contains(code, d) {
+			return d, true
+		}
+	}
+	return "", false
+}
@@ -52,4 +52,18 @@ func TestLatex(t *testing.T) {
 		})
 	}
 
-}
\ No newline at end of file
+}
+
+func TestLatexMinted(t *testing.T) {
+	input := "```python title=\"main.py\" linenums=\"3\" hl_lines=\"4 5\"\nprint(1)\n```\n"
+	expected := "\\noindent\\textbf{main.py}\\par\n\\begin{minted}[linenos,firstnumber=3,highlightlines={4,5}]{py3}\nprint(1)\n\\end{minted}\n\n"
+	doc := md2json.MarkdownParse([]byte(input))
+	opts := md2json.TexOptions{Highlight: "minted", Languages: map[string]string{"python": "py3"}}
+	tex, err := md2json.LatexOptions(&doc, opts)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(tex) != expected {
+		t.Errorf("Latex()\nactual\n%s\nexpected\n%s", tex, expected)
+	}
+}
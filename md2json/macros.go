@@ -15,6 +15,7 @@ func replaceMacros(src []byte, macros map[string]string, fp string) ([]byte, boo
 
 	open := []byte("<m>")
 	close := []byte("</m>")
+	full := src
 
 	for len(src) > 0 {
 		i := bytes.Index(src, open)
@@ -24,12 +25,12 @@ func replaceMacros(src []byte, macros map[string]string, fp string) ([]byte, boo
 		}
 		idx := bytes.Index(src[i:], close)
 		if idx < 0 {
-			return output.Bytes(), false, errors.New(fmt.Sprintf("Unmatched closing <m> tag in file %s", fp))
+			return output.Bytes(), false, errors.New(fmt.Sprintf("Unmatched closing <m> tag in file %s", textLocation(full, len(full)-len(src)+i, fp)))
 		}
 		name := src[i+len(open) : i+idx]
 		value, ok2 := macros[string(name)]
 		if !ok2 {
-			return output.Bytes(), false, errors.New(fmt.Sprintf("Not found macro '%s' in file %s", name, fp))
+			return output.Bytes(), false, errors.New(fmt.Sprintf("Not found macro '%s' in file %s", name, textLocation(full, len(full)-len(src)+i, fp)))
 		}
 		output.Write(src[:i])
 		output.Write([]byte(value))
@@ -39,6 +40,13 @@ func replaceMacros(src []byte, macros map[string]string, fp string) ([]byte, boo
 	return output.Bytes(), dirty, nil
 }
 
+// textLocation formats offset in raw markdown source as file:line:col
+func textLocation(src []byte, offset int, fp string) string {
+	st := newParserState(src, Point{Line: 1, Column: 1})
+	p := st.pointAt(src[offset:])
+	return fmt.Sprintf("%s:%d:%d", fp, p.Line, p.Column)
+}
+
 func SubstituteMacrosFromFile(macrosPath string, inDir string, outDir string) error {
 	macros := make(map[string]string)
 
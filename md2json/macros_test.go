@@ -4,6 +4,7 @@ import (
 	"log"
 	"marktome/md2json"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -31,3 +32,17 @@ func TestMacros(t *testing.T) {
 		t.Errorf("Macros failure: %s", d3)
 	}
 }
+
+func TestMacrosUnknownLocation(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "test-macros-unknown")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.WriteFile(dir+"/unknown.md", []byte("# Title\n\nSee <m>nomacro</m>\n"), os.ModePerm)
+	err = md2json.SubstituteMacrosPath(map[string]string{}, dir+"/unknown.md", dir+"/out.md")
+	if err == nil || !strings.Contains(err.Error(), dir+"/unknown.md:3:5") {
+		t.Errorf("Macros error without location: %v", err)
+	}
+}
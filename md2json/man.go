@@ -0,0 +1,408 @@
+package md2json
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manTextReplacer escapes characters that roff treats specially. Hyphens
+// become minus signs, so options can be copied from the page, and
+// non-breaking spaces become unpaddable ones.
+var manTextReplacer = strings.NewReplacer(
+	`\`, `\e`,
+	`-`, `\-`,
+	" ", `\ `,
+)
+
+// manBreak is line break inside paragraph, manLines makes it request
+const manBreak = "\n\x00.br\n"
+
+// Man renders the document as man page. Section, date, source and manual
+// of the page header are taken from meta attributes of the document, name
+// is the title of the first heading or title attribute.
+func Man(n *Node) []byte {
+	doc, footnotes := splitFootnotes(n)
+	var text bytes.Buffer
+	if manHasTables(&doc) {
+		// preprocessor line asks man to run tbl
+		text.WriteString("'\\\" t\n")
+	}
+
+	// name of the page is the first heading unless meta has title
+	title, ok := n.Attributes["title"]
+	first := -1
+	for i, ch := range doc.Children {
+		if ch.Type == Heading && ch.Attributes["level"] == "1" {
+			first = i
+			break
+		}
+	}
+	if !ok && first >= 0 {
+		title = doc.Children[first].Literal
+	}
+	section, ok := n.Attributes["section"]
+	if !ok {
+		section = "1"
+	}
+	text.WriteString(fmt.Sprintf(".TH %s %s %s %s %s\n",
+		manQuote(strings.ToUpper(title)), manQuote(section), manQuote(n.Attributes["date"]),
+		manQuote(n.Attributes["source"]), manQuote(n.Attributes["manual"])))
+
+	for i, ch := range doc.Children {
+		if i == first {
+			// the heading is in the header already
+			continue
+		}
+		text.Write(writeManBlock(&ch))
+	}
+
+	if len(footnotes) > 0 {
+		text.WriteString(".SH \"NOTES\"\n")
+		for _, fn := range footnotes {
+			text.WriteString(fmt.Sprintf(".IP %s 4\n", manQuote("["+fn.Attributes["id"]+"]")))
+			for i, ch := range fn.Children {
+				if i > 0 {
+					text.WriteString(".IP\n")
+				}
+				text.Write(manLines(writeManInlines(&ch, "R")))
+			}
+		}
+	}
+	return text.Bytes()
+}
+
+// manHasTables looks for tables anywhere in the tree, in lists and
+// admonitions too.
+func manHasTables(n *Node) bool {
+	if n.Type == Table {
+		return true
+	}
+	for i := range n.Children {
+		if manHasTables(&n.Children[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// manQuote makes argument of a request.
+func manQuote(arg string) string {
+	return `"` + strings.ReplaceAll(manTextReplacer.Replace(arg), `"`, `\(dq`) + `"`
+}
+
+// manLines breaks text to input lines. Leading spaces would break the
+// line and leading dot or quote would start a request, so spaces are
+// trimmed and dot or quote escaped.
+func manLines(text []byte) []byte {
+	var lines bytes.Buffer
+	for _, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line[0] == 0 {
+			lines.WriteString(line[1:] + "\n")
+			continue
+		}
+		if line[0] == '.' || line[0] == '\'' {
+			lines.WriteString(`\&`)
+		}
+		lines.WriteString(line)
+		lines.WriteString("\n")
+	}
+	return lines.Bytes()
+}
+
+func writeManBlocks(n *Node) []byte {
+	var text bytes.Buffer
+	for _, ch := range n.Children {
+		text.Write(writeManBlock(&ch))
+	}
+	return text.Bytes()
+}
+
+func writeManBlock(n *Node) []byte {
+	switch n.Type {
+	case Heading:
+		if n.Attributes["level"] == "1" || n.Attributes["level"] == "2" {
+			return []byte(".SH " + manQuote(strings.ToUpper(n.Literal)) + "\n")
+		}
+		return []byte(".SS " + manQuote(n.Literal) + "\n")
+	case Paragraph:
+		return append([]byte(".PP\n"), manLines(writeManInlines(n, "R"))...)
+	case List:
+		return writeManList(n)
+	case Admonition:
+		// title leads the first paragraph, other blocks are indented
+		var text bytes.Buffer
+		inlines := []byte{}
+		if title := n.AdmonitionTitle(); title != "" {
+			inlines = []byte(`\fB` + manTextReplacer.Replace(title) + `:\fR `)
+		}
+		rest := n.Children
+		if len(rest) > 0 && rest[0].Type == Paragraph {
+			inlines = append(inlines, writeManInlines(&rest[0], "R")...)
+			rest = rest[1:]
+		}
+		text.WriteString(".PP\n")
+		text.Write(manLines(inlines))
+		if len(rest) > 0 {
+			text.WriteString(".RS 4\n")
+			text.Write(writeManBlocks(&Node{Children: rest}))
+			text.WriteString(".RE\n")
+		}
+		return text.Bytes()
+	case Tabs:
+		// every tab is bold title with indented blocks
+		var text bytes.Buffer
+		for _, tab := range n.Children {
+			text.WriteString(".PP\n")
+			text.Write(manLines([]byte(`\fB` + manTextReplacer.Replace(tab.Attributes["title"]) + `\fR`)))
+			text.WriteString(".RS 4\n")
+			text.Write(writeManBlocks(&tab))
+			text.WriteString(".RE\n")
+		}
+		return text.Bytes()
+	case CodeFence:
+		var text bytes.Buffer
+		text.WriteString(".PP\n.RS 4\n.nf\n")
+		for _, line := range strings.Split(strings.TrimSuffix(n.Literal, "\n"), "\n") {
+			line = manTextReplacer.Replace(line)
+			if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+				text.WriteString(`\&`)
+			}
+			text.WriteString(line + "\n")
+		}
+		text.WriteString(".fi\n.RE\n")
+		return text.Bytes()
+	case Blockquote:
+		return []byte(".RS 4\n" + string(writeManBlocks(n)) + ".RE\n")
+	case Table:
+		return writeManTable(n)
+	case ThematicBreak:
+		return []byte(".PP\n")
+	case HTML, Comment, "NewPage":
+		return []byte{}
+	default:
+		fmt.Println("Type", n.Type)
+	}
+	return []byte{}
+}
+
+// manTagSeparators end term of definition-like list item
+var manTagSeparators = []string{": ", " - ", " – ", " — "}
+
+// manTag splits definition-like list item, which starts with bold or
+// code, like "`-o` *file*: write output", to term and definition. Without
+// separator the term is the starting bold or code.
+func manTag(item *Node) (Node, Node, bool) {
+	if len(item.Children) == 0 || item.Children[0].Type != Paragraph {
+		return Node{}, Node{}, false
+	}
+	p := item.Children[0]
+	if len(p.Children) == 0 || (p.Children[0].Type != Code && p.Children[0].Type != Bold) {
+		return Node{}, Node{}, false
+	}
+	term := Node{Type: Paragraph, Children: []Node{p.Children[0]}}
+	rest := Node{Type: Paragraph, Children: append([]Node{}, p.Children[1:]...)}
+	for i := 1; i < len(p.Children); i++ {
+		ch := p.Children[i]
+		if ch.Type != Text {
+			continue
+		}
+		for _, sep := range manTagSeparators {
+			at := strings.Index(ch.Literal, sep)
+			if at < 0 {
+				continue
+			}
+			term.Children = append([]Node{}, p.Children[:i]...)
+			term.Children = append(term.Children, Node{Type: Text, Literal: ch.Literal[:at]})
+			rest.Children = append([]Node{{Type: Text, Literal: ch.Literal[at+len(sep):]}}, p.Children[i+1:]...)
+			return term, rest, true
+		}
+	}
+	if len(rest.Children) > 0 && rest.Children[0].Type == Text {
+		first := rest.Children[0]
+		first.Literal = strings.TrimLeft(first.Literal, " ")
+		rest.Children[0] = first
+	}
+	return term, rest, true
+}
+
+func writeManList(n *Node) []byte {
+	var text bytes.Buffer
+	_, ordered := n.Attributes["ordered"]
+	number := 1
+	if start, ok := n.Attributes["start"]; ok {
+		fmt.Sscan(start, &number)
+	}
+	for i, item := range n.Children {
+		children := item.Children
+		term, rest, ok := manTag(&item)
+		if ok && !ordered {
+			text.WriteString(".TP\n")
+			text.Write(manLines(writeManInlines(&term, "R")))
+			text.Write(manLines(writeManInlines(&rest, "R")))
+			children = children[1:]
+		} else {
+			bullet := `\(bu`
+			indent := 2
+			if ordered {
+				bullet = fmt.Sprintf("%d.", number+i)
+				indent = 4
+			}
+			switch item.Attributes["checked"] {
+			case "true":
+				bullet += ` [x]`
+				indent += 4
+			case "false":
+				bullet += ` [ ]`
+				indent += 4
+			}
+			text.WriteString(fmt.Sprintf(".IP \"%s\" %d\n", bullet, indent))
+			if len(children) > 0 && children[0].Type == Paragraph {
+				text.Write(manLines(writeManInlines(&children[0], "R")))
+				children = children[1:]
+			}
+		}
+		if len(children) > 0 {
+			text.WriteString(".RS\n")
+			for _, ch := range children {
+				text.Write(writeManBlock(&ch))
+			}
+			text.WriteString(".RE\n")
+		}
+	}
+	return text.Bytes()
+}
+
+func writeManTable(n *Node) []byte {
+	var text bytes.Buffer
+	header := n.Children[0]
+	body := n.Children[1]
+	formats := []string{}
+	heads := []string{}
+	for _, th := range header.Children {
+		format := "l"
+		switch th.Attributes["align"] {
+		case "center":
+			format = "c"
+		case "right":
+			format = "r"
+		}
+		formats = append(formats, format)
+		if th.Type == Text {
+			heads = append(heads, `\fB`+manTextReplacer.Replace(th.Literal)+`\fR`)
+		} else {
+			heads = append(heads, `\fB`+string(writeManInlines(&th, "B"))+`\fR`)
+		}
+	}
+	text.WriteString(".PP\n.TS\ntab(\t);\n")
+	text.WriteString(strings.Join(formats, " ") + ".\n")
+	text.WriteString(strings.Join(heads, "\t") + "\n_\n")
+	for _, row := range body.Children {
+		cells := []string{}
+		for _, cell := range row.Children {
+			cells = append(cells, strings.ReplaceAll(string(writeManInlines(&cell, "R")), "\n", " "))
+		}
+		text.WriteString(strings.Join(cells, "\t") + "\n")
+	}
+	text.WriteString(".TE\n")
+	return text.Bytes()
+}
+
+// manFont switches font inside font of the parent, so bold inside
+// emphasis is bold italic and the font of the parent comes back after it.
+func manFont(parent string, font string) string {
+	if parent == "R" || parent == font {
+		return font
+	}
+	if strings.Contains(parent, font) {
+		return parent
+	}
+	return "BI"
+}
+
+func manFontEscape(font string) string {
+	if len(font) == 1 {
+		return `\f` + font
+	}
+	return `\f(` + font
+}
+
+func writeManInlines(n *Node, font string) []byte {
+	var text bytes.Buffer
+	for _, ch := range n.Children {
+		text.Write(writeManInline(&ch, font))
+	}
+	return text.Bytes()
+}
+
+// writeManInliner writes content of emphasis-like node, which is either
+// plain literal or nested inline nodes, in the font.
+// Empty font keeps font of the parent.
+func writeManInliner(n *Node, parent string, font string) []byte {
+	if font == "" {
+		font = parent
+	} else {
+		font = manFont(parent, font)
+	}
+	inner := []byte(manTextReplacer.Replace(n.Literal))
+	if n.Children != nil {
+		inner = writeManInlines(n, font)
+	}
+	if font == parent {
+		return inner
+	}
+	return []byte(manFontEscape(font) + string(inner) + manFontEscape(parent))
+}
+
+func writeManInline(n *Node, font string) []byte {
+	switch n.Type {
+	case Text:
+		return []byte(manTextReplacer.Replace(n.Literal))
+	case Bold:
+		return writeManInliner(n, font, "B")
+	case Emphasis:
+		return writeManInliner(n, font, "I")
+	case Strikethrough:
+		return writeManInliner(n, font, "")
+	case Code:
+		return writeManInliner(n, font, "B")
+	case Link:
+		if _, ok := n.Attributes["autolink"]; ok {
+			return []byte(manTextReplacer.Replace(n.Attributes["href"]))
+		}
+		text := writeManInliner(n, font, "")
+		href := n.Attributes["href"]
+		// links to other pages of the documentation lead nowhere in man
+		if strings.Contains(href, "://") || strings.HasPrefix(href, "mailto:") {
+			text = append(text, []byte(" <"+manTextReplacer.Replace(linkUrl(n))+">")...)
+		}
+		return text
+	case Image:
+		return []byte("[" + manTextReplacer.Replace(n.Literal) + "]")
+	case FootnoteRef:
+		return []byte("[" + manTextReplacer.Replace(n.Attributes["id"]) + "]")
+	case LineBreak:
+		return []byte(manBreak)
+	case HTML, Comment:
+		return []byte{}
+	default:
+		fmt.Println("Type", n.Type)
+	}
+	return []byte{}
+}
+
+// Json2Man writes document as man page.
+func Json2Man(input string, output string) error {
+	doc, err := ReadJson(input)
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	return os.WriteFile(output, Man(&doc), os.ModePerm)
+}
@@ -0,0 +1,26 @@
+package md2json_test
+
+import (
+	"bytes"
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMan(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/man/*.md")
+	for _, fp := range paths {
+		name := strings.TrimSuffix(fp, ".md")
+		t.Run(name, func(t *testing.T) {
+			input, _ := os.ReadFile(fp)
+			expected, _ := os.ReadFile(name + ".man")
+			doc := md2json.MarkdownParse(input)
+			text := md2json.Man(&doc)
+			if !bytes.Equal(text, expected) {
+				t.Errorf("Man()\nactual\n%s\nexpected\n%s", text, expected)
+			}
+		})
+	}
+}
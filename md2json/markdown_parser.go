@@ -5,17 +5,28 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 func MarkdownParse(source []byte) Node {
-	st := ParserState{
-		source: source,
-	}
-	node := parseDocument(&st)
+	st := newParserState(source, Point{Line: 1, Column: 1})
+	node := parseDocument(st)
+	resolveReferenceLinks(&node)
 	return node
 }
 
+// parseNested parses block content cut out of the document, i.e. body of
+// a list item with indentation stripped. start is the place of the first
+// byte of source in the document, start.Column is applied to every line.
+func parseNested(source []byte, start Point) []Node {
+	st := newParserState(source, start)
+	doc := parseDocument(st)
+	return doc.Children
+}
+
 func Md2Json(input string, output string) error {
 	source, err := os.ReadFile(input)
 	if err != nil {
@@ -27,13 +38,14 @@ func Md2Json(input string, output string) error {
 }
 
 func parseDocument(st *ParserState) Node {
+	s1 := st.source
 	node := Node{
 		Type:       Document,
 		Attributes: map[string]string{},
 		Children:   []Node{},
 	}
 
-	if st.startsWith("---\n") {
+	if st.startsWith("---\n") && isMetaStart(st) {
 		parseMeta(st, &node)
 	}
 
@@ -54,33 +66,67 @@ func parseDocument(st *ParserState) Node {
 		if parseHeading(st, &node) {
 			continue
 		}
+		if parseBlockquote(st, &node) {
+			continue
+		}
+		if parseThematicBreak(st, &node) {
+			continue
+		}
 		if parseList(st, &node) {
 			continue
 		}
 		if parseAdmonition(st, &node) {
 			continue
 		}
+		if parseTabs(st, &node) {
+			continue
+		}
 		if parseCodeFence(st, &node) {
 			continue
 		}
 		if parseTable(st, &node) {
 			continue
 		}
+		if parseFootnoteDef(st, &node) {
+			continue
+		}
+		if parseLinkDefinition(st, &node) {
+			continue
+		}
 		// goes last
 		parseParagraph(st, &node)
 	}
+	node.Position = st.span(s1)
 	return node
 }
 
 type InlineParserState struct {
-	source   []byte
-	children []Node
-	text     []byte
+	source    []byte
+	children  []Node
+	text      []byte
+	pos       Point
+	textStart Point
+	// column of the first character on every next line of source
+	margin int
+}
+
+func newInlineParserState(source []byte, start Point, margin int) *InlineParserState {
+	return &InlineParserState{
+		source:   source,
+		children: []Node{},
+		text:     []byte{},
+		pos:      start,
+		margin:   margin,
+	}
 }
 
 func (st *InlineParserState) flushText() {
 	if len(st.text) > 0 {
-		node := Node{Type: Text, Literal: string(st.text)}
+		node := Node{
+			Type:     Text,
+			Literal:  string(st.text),
+			Position: &Position{Start: st.textStart, End: st.pos},
+		}
 		st.children = append(st.children, node)
 		st.text = []byte{}
 	}
@@ -92,9 +138,21 @@ func (st *InlineParserState) consumeN(n int) []byte {
 	}
 	s := st.source[:n]
 	st.source = st.source[n:]
+	for _, c := range s {
+		if c == '\n' {
+			st.pos.Line += 1
+			st.pos.Column = st.margin
+		} else if utf8.RuneStart(c) {
+			st.pos.Column += 1
+		}
+	}
 	return s
 }
 
+func (st *InlineParserState) span(start Point) *Position {
+	return &Position{Start: start, End: st.pos}
+}
+
 func (st *InlineParserState) startsWith(s []byte) bool {
 	if len(s) >= len(st.source) {
 		return false
@@ -102,6 +160,27 @@ func (st *InlineParserState) startsWith(s []byte) bool {
 	return bytes.Equal(s, st.source[:len(s)])
 }
 
+// parseLineBreak reads hard line break: two or more spaces or backslash
+// before the end of line. It is ignored in the end of block.
+func (st *InlineParserState) parseLineBreak() {
+	spaces := 0
+	for spaces < len(st.source) && st.source[spaces] == ' ' {
+		spaces++
+	}
+	size := 0
+	if spaces >= 2 && st.startsWith([]byte(strings.Repeat(" ", spaces)+"\n")) {
+		size = spaces + 1
+	} else if st.startsWith([]byte("\\\n")) {
+		size = 2
+	} else {
+		return
+	}
+	st.flushText()
+	start := st.pos
+	st.consumeN(size)
+	st.children = append(st.children, Node{Type: LineBreak, Position: st.span(start)})
+}
+
 func (st *InlineParserState) parseCode() {
 	if !st.startsWith([]byte{'`'}) {
 		return
@@ -111,12 +190,14 @@ func (st *InlineParserState) parseCode() {
 		return
 	}
 	st.flushText()
+	start := st.pos
 	st.consumeN(1)
 	text := st.consumeN(i)
 	st.consumeN(1)
 	node := Node{
-		Type:    Code,
-		Literal: string(text),
+		Type:     Code,
+		Literal:  string(text),
+		Position: st.span(start),
 	}
 	st.children = append(st.children, node)
 }
@@ -132,16 +213,19 @@ func (st *InlineParserState) parseInliner(symbol []byte, typ Kind) {
 	}
 	if i > 0 {
 		st.flushText()
+		start := st.pos
 		st.consumeN(len(symbol))
 		node := Node{Type: typ}
-		children := parseText(st.consumeN(i))
+		inner := st.pos
+		children := parseText(st.consumeN(i), inner, st.margin)
 		if len(children) == 1 && children[0].Type == Text {
 			node.Literal = children[0].Literal
 		} else {
 			node.Children = children
 		}
-		st.children = append(st.children, node)
 		st.consumeN(len(symbol))
+		node.Position = st.span(start)
+		st.children = append(st.children, node)
 	}
 }
 
@@ -151,26 +235,35 @@ func (st *InlineParserState) parseHtml() {
 	br := "<br>"
 	if st.startsWith([]byte(br)) {
 		st.flushText()
+		start := st.pos
 		st.consumeN(len(br))
 		node := Node{
 			Type:       HTML,
 			Attributes: map[string]string{"tag": "br"},
+			Position:   st.span(start),
 		}
 		st.children = append(st.children, node)
 		return
 	}
+	// end tags are read with their start tags
 	if st.startsWith([]byte{'<', '/'}) {
 		return
 	}
+	if st.startsWith([]byte("<!--")) {
+		st.parseInlineComment()
+		return
+	}
 	if !st.startsWith([]byte{'<'}) {
 		return
 	}
+	// other < is a tag only before a name, a < b is plain text
+	if len(st.source) < 2 || !isAsciiLetter(st.source[1]) || bytes.IndexByte(st.source, '>') < 0 {
+		return
+	}
 	st.flushText()
+	start := st.pos
 	st.consumeN(1)
 	tagEnd := bytes.Index(st.source, []byte{'>'})
-	if tagEnd < 0 {
-		return
-	}
 	nameEnd := bytes.Index(st.source[:tagEnd], []byte{' '})
 	if nameEnd < 0 {
 		nameEnd = tagEnd
@@ -203,7 +296,10 @@ func (st *InlineParserState) parseHtml() {
 		attrs[string(attrMatches[i][1])] = string(attrMatches[i][2])
 	}
 	var text string
-	if !selfClosing {
+	textStart := st.pos
+	// void elements like <img> have no content and no end tag, <link> is
+	// ours and leads to anchor
+	if !selfClosing && (!htmlVoidElements[tag] || tag == "link") {
 		closure := append([]byte{'<', '/'}, tagName...)
 		closure = append(closure, '>')
 		finish := bytes.Index(st.source, closure)
@@ -219,18 +315,33 @@ func (st *InlineParserState) parseHtml() {
 	node := Node{
 		Type:       HTML,
 		Attributes: attrs,
+		Position:   st.span(start),
 	}
 	if tag == "if" {
-		node.Children = parseText([]byte(text))
+		node.Children = parseText([]byte(text), textStart, st.margin)
 	} else if tag == "details" {
-		doc := MarkdownParse([]byte(text))
-		node.Children = doc.Children
+		node.Children = parseNested([]byte(text), textStart)
 	} else {
 		node.Literal = text
 	}
 	st.children = append(st.children, node)
 }
 
+// parseInlineComment reads <!-- comment --> inside the text, which has no
+// end runs to the end of the text.
+func (st *InlineParserState) parseInlineComment() {
+	st.flushText()
+	start := st.pos
+	st.consumeN(len("<!--"))
+	finish := bytes.Index(st.source, []byte("-->"))
+	if finish < 0 {
+		finish = len(st.source)
+	}
+	text := st.consumeN(finish)
+	st.consumeN(len("-->"))
+	st.children = append(st.children, Node{Type: Comment, Literal: string(text), Position: st.span(start)})
+}
+
 func (st *InlineParserState) readLink() (Node, bool) {
 	if !st.startsWith([]byte{'['}) {
 		return Node{}, false
@@ -269,14 +380,23 @@ func (st *InlineParserState) parseLink() {
 		return
 	}
 	st.flushText()
+	start := st.pos
 	title := string(st.source[1:titleEnd])
-	fullUrl := strings.Split(string(st.source[titleEnd+2:titleEnd+urlEnd]), "#")
+	url := string(st.source[titleEnd+2 : titleEnd+urlEnd])
 	st.consumeN(titleEnd + urlEnd + 1)
 	node := Node{
 		Type:       Link,
 		Attributes: map[string]string{},
 		Literal:    title,
+		Position:   st.span(start),
 	}
+	setLinkUrl(&node, url)
+	st.children = append(st.children, node)
+}
+
+// setLinkUrl splits url to href and anchor attributes of the link.
+func setLinkUrl(node *Node, url string) {
+	fullUrl := strings.Split(url, "#")
 	if len(fullUrl) == 1 {
 		node.Attributes["href"] = fullUrl[0]
 	} else if len(fullUrl[0]) == 0 {
@@ -285,6 +405,90 @@ func (st *InlineParserState) parseLink() {
 		node.Attributes["href"] = fullUrl[0]
 		node.Attributes["anchor"] = fullUrl[1]
 	}
+}
+
+var referenceLinkRegexp = regexp.MustCompile(`^\[([^\[\]]+)\]\[([^\[\]]*)\]`)
+
+// parseReferenceLink reads [text][ref] and [text][]. Link gets its url
+// later in resolveReferenceLinks when all definitions are known.
+func (st *InlineParserState) parseReferenceLink() {
+	m := referenceLinkRegexp.FindSubmatch(st.source)
+	if m == nil {
+		return
+	}
+	st.flushText()
+	start := st.pos
+	st.consumeN(len(m[0]))
+	ref := m[2]
+	if len(ref) == 0 {
+		ref = m[1]
+	}
+	node := Node{
+		Type:       Link,
+		Attributes: map[string]string{"ref": string(ref)},
+		Literal:    string(m[1]),
+		Position:   st.span(start),
+	}
+	st.children = append(st.children, node)
+}
+
+var footnoteRefRegexp = regexp.MustCompile(`^\[\^([^\]\s]+)\]`)
+
+func (st *InlineParserState) parseFootnoteRef() {
+	m := footnoteRefRegexp.FindSubmatch(st.source)
+	if m == nil {
+		return
+	}
+	st.flushText()
+	start := st.pos
+	st.consumeN(len(m[0]))
+	node := Node{
+		Type:       FootnoteRef,
+		Attributes: map[string]string{"id": string(m[1])},
+		Position:   st.span(start),
+	}
+	st.children = append(st.children, node)
+}
+
+// parseAutolink reads <https://...> and bare http(s) urls into links.
+// Way of writing is kept in autolink attribute: angle or bare.
+func (st *InlineParserState) parseAutolink() {
+	var url []byte
+	size := 0
+	kind := "bare"
+	if st.startsWith([]byte("<http://")) || st.startsWith([]byte("<https://")) {
+		end := bytes.IndexAny(st.source, "> \n")
+		if end < 0 || st.source[end] != '>' {
+			return
+		}
+		url = st.source[1:end]
+		size = end + 1
+		kind = "angle"
+	} else if st.startsWith([]byte("http://")) || st.startsWith([]byte("https://")) {
+		if len(st.text) > 0 && !bytes.ContainsAny(st.text[len(st.text)-1:], " \t\n(") {
+			return
+		}
+		end := bytes.IndexAny(st.source, " \t\n<")
+		if end < 0 {
+			end = len(st.source)
+		}
+		url = bytes.TrimRight(st.source[:end], ".,:;!?")
+		if bytes.HasSuffix(url, []byte{')'}) && !bytes.Contains(url, []byte{'('}) {
+			url = url[:len(url)-1]
+		}
+		size = len(url)
+	} else {
+		return
+	}
+	st.flushText()
+	start := st.pos
+	st.consumeN(size)
+	node := Node{
+		Type:       Link,
+		Attributes: map[string]string{"href": string(url), "autolink": kind},
+		Literal:    string(url),
+		Position:   st.span(start),
+	}
 	st.children = append(st.children, node)
 }
 
@@ -298,6 +502,7 @@ func (st *InlineParserState) parseImage() {
 		return
 	}
 	st.flushText()
+	start := st.pos
 	title := string(st.source[2:titleEnd])
 	url := string(st.source[titleEnd+2 : titleEnd+urlEnd])
 	st.consumeN(titleEnd + urlEnd + 1)
@@ -305,23 +510,49 @@ func (st *InlineParserState) parseImage() {
 		Type:       Image,
 		Attributes: map[string]string{"src": url},
 		Literal:    title,
+		Position:   st.span(start),
 	}
 	st.children = append(st.children, node)
 }
 
 func parseParagraph(st *ParserState, node *Node) bool {
 	s1 := st.source
-	for !st.eof() && !st.startsWith("\n") && !st.startsWith("```") {
+	setext := false
+	for !st.eof() && !st.startsWith("\n") && !st.startsWith("```") && !st.startsWith(">") {
 		st.consumeLine()
+		next := st.peekLine(0)
+		if isSetextUnderline(next) {
+			setext = true
+			break
+		}
+		if isThematicBreak(next) {
+			break
+		}
 	}
 	l := len(s1) - len(st.source)
 	block := bytes.TrimSuffix(s1[:l], []byte{'\n'})
-	children := parseText(block)
+	if setext {
+		underline := st.consumeLine()
+		n1 := Node{
+			Type:       Heading,
+			Attributes: map[string]string{"level": "2"},
+		}
+		if underline[0] == '=' {
+			n1.Attributes["level"] = "1"
+		}
+		h := bytes.ReplaceAll(bytes.TrimSpace(block), []byte{'\n'}, []byte{' '})
+		parseHeadingText(h, &n1)
+		n1.Position = st.span(s1)
+		node.Children = append(node.Children, n1)
+		return true
+	}
+	children := parseText(block, st.pointAt(s1), st.origin.Column)
 	if len(children) > 0 {
 		n := Node{
 			Type:       Paragraph,
 			Attributes: map[string]string{},
 			Children:   children,
+			Position:   st.span(s1),
 		}
 		node.Children = append(node.Children, n)
 		return true
@@ -334,63 +565,174 @@ func parseTable(st *ParserState, node *Node) bool {
 		return false
 	}
 	source := st.source
+	headerStart := st.point()
 	headerText := st.consumeLine()
-	if !st.startsWith("|-") {
+	headerPosition := st.span(source)
+	if !tableDelimiterRegexp.Match(st.peekLine(0)) {
 		st.source = source
 		return false
 	}
-	st.consumeLine() // Line with |----|---|
+	delimiterStart := st.source
+	delimiter := st.consumeLine() // Line with |----|:---:|
 	if !st.startsWith("|") {
 		st.source = source
 		return false
 	}
+	aligns := []string{}
+	for _, c := range splitTableRow(delimiter, st.pointAt(delimiterStart)) {
+		aligns = append(aligns, tableAlign(c.text))
+	}
 
-	header := Node{Type: TableHead, Children: make([]Node, 0)}
-	headerParts := bytes.Split(headerText, []byte{'|'})
-	for i, p := range headerParts {
-		p_ := strings.Trim(string(p), " \t")
-		if i > 0 && i < len(headerParts)-1 {
-			header.Children = append(header.Children, Node{Type: Text, Literal: p_})
+	header := Node{Type: TableHead, Children: make([]Node, 0), Position: headerPosition}
+	for i, c := range splitTableRow(headerText, headerStart) {
+		th := Node{
+			Type:     TableCell,
+			Children: parseText(c.text, c.start, st.origin.Column),
+			Position: c.span(),
 		}
+		if i < len(aligns) && aligns[i] != "" {
+			th.Attributes = map[string]string{"align": aligns[i]}
+		}
+		header.Children = append(header.Children, th)
 	}
 
+	bodyStart := st.source
 	body := Node{Type: TableBody, Children: make([]Node, 0)}
 	for st.startsWith("|") {
 		row := Node{Type: TableRow, Children: make([]Node, 0)}
+		rowStart := st.source
 		line := st.consumeLine()
-		rowParts := bytes.Split(line, []byte{'|'})
-		for i, p := range rowParts {
-			p_ := strings.Trim(string(p), " \t")
-			cell := parseText([]byte(p_))
-			if i > 0 && i < len(rowParts)-1 {
-				row.Children = append(row.Children, Node{Type: TableCell, Children: cell})
-			}
+		for _, c := range splitTableRow(line, st.pointAt(rowStart)) {
+			cell := parseText(c.text, c.start, st.origin.Column)
+			row.Children = append(row.Children, Node{Type: TableCell, Children: cell, Position: c.span()})
 		}
+		row.Position = st.span(rowStart)
 		body.Children = append(body.Children, row)
 	}
+	body.Position = st.span(bodyStart)
 
-	table := Node{Type: Table, Children: []Node{header, body}}
+	table := Node{Type: Table, Children: []Node{header, body}, Position: st.span(source)}
+	parseTableHint(node, &table)
 	node.Children = append(node.Children, table)
 	return true
 }
 
-func parseText(source []byte) []Node {
-	st := InlineParserState{
-		source:   source,
-		children: []Node{},
-		text:     []byte{},
+// parseTableHint moves attributes from <!-- table widths="3,1,2" -->
+// comment right before the table into the table itself.
+func parseTableHint(node *Node, table *Node) {
+	last := len(node.Children) - 1
+	if last < 0 || node.Children[last].Type != Comment {
+		return
+	}
+	hint := strings.TrimSpace(node.Children[last].Literal)
+	if !strings.HasPrefix(hint, "table ") {
+		return
 	}
+	table.Attributes = map[string]string{}
+	for _, m := range tagAttrsRegexp.FindAllStringSubmatch(hint, -1) {
+		table.Attributes[m[1]] = m[2]
+	}
+	if node.Children[last].Position != nil {
+		table.Position.Start = node.Children[last].Position.Start
+	}
+	node.Children = node.Children[:last]
+}
+
+var tableDelimiterRegexp = regexp.MustCompile(`^\|?(\s*:?-+:?\s*\|)*\s*:?-+:?\s*\|?\s*$`)
+
+// tableAlign reads column alignment from ---, :---, :---: and ---:
+func tableAlign(delimiter []byte) string {
+	left := bytes.HasPrefix(delimiter, []byte{':'})
+	right := bytes.HasSuffix(delimiter, []byte{':'})
+	switch {
+	case left && right:
+		return "center"
+	case left:
+		return "left"
+	case right:
+		return "right"
+	}
+	return ""
+}
+
+type tableCell struct {
+	text  []byte
+	start Point
+}
+
+func (c *tableCell) span() *Position {
+	end := c.start
+	end.Column += utf8.RuneCount(c.text)
+	return &Position{Start: c.start, End: end}
+}
+
+// splitTableRow cuts | a | b | line into trimmed cells, remembering where
+// every cell starts in the source. Escaped \| and pipes inside `code` do not
+// split cells, \| is unescaped in the cell text.
+func splitTableRow(line []byte, start Point) []tableCell {
+	parts := [][]byte{}
+	inCode := false
+	begin := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if i+1 < len(line) && line[i+1] == '|' {
+				i++
+			}
+		case '`':
+			if inCode || bytes.IndexByte(line[i+1:], '`') >= 0 {
+				inCode = !inCode
+			}
+		case '|':
+			if !inCode {
+				parts = append(parts, line[begin:i])
+				begin = i + 1
+			}
+		}
+	}
+	parts = append(parts, line[begin:])
+
+	cells := []tableCell{}
+	column := start.Column
+	for i, p := range parts {
+		last := i == len(parts)-1
+		if i > 0 && !(last && len(bytes.TrimSpace(p)) == 0) {
+			text := bytes.TrimLeft(p, " \t")
+			cellStart := Point{Line: start.Line, Column: column + len(p) - len(text)}
+			text = bytes.ReplaceAll(bytes.TrimRight(text, " \t"), []byte("\\|"), []byte{'|'})
+			cells = append(cells, tableCell{text: text, start: cellStart})
+		}
+		column += utf8.RuneCount(p) + 1
+	}
+	return cells
+}
+
+func isAsciiLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseText parses inline content. start is the place of source in the
+// document and margin is the column where every next line of source starts.
+func parseText(source []byte, start Point, margin int) []Node {
+	st := newInlineParserState(source, start, margin)
 	for len(st.source) > 0 {
 		l1 := len(st.source)
+		st.parseLineBreak()
 		st.parseCode()
 		st.parseInliner([]byte{'*', '*'}, Bold)
 		st.parseInliner([]byte{'*'}, Emphasis)
+		st.parseInliner([]byte{'~', '~'}, Strikethrough)
+		st.parseFootnoteRef()
+		st.parseReferenceLink()
 		st.parseLink()
 		st.parseImage()
+		st.parseAutolink()
 		st.parseHtml()
 		if l1 == len(st.source) {
-			st.text = append(st.text, st.source[0])
-			st.source = st.source[1:]
+			if len(st.text) == 0 {
+				st.textStart = st.pos
+			}
+			st.text = append(st.text, st.consumeN(1)...)
 		}
 	}
 	st.flushText()
@@ -403,10 +745,11 @@ func parseComment(st *ParserState, node *Node) bool {
 	if st.startsWith(opening) {
 		i := bytes.Index(st.source[len(opening):], []byte(closing))
 		if i >= 0 {
+			s1 := st.source
 			st.consumeN(len(opening))
 			text := st.consumeN(i)
 			st.consumeN(len(closing))
-			node.Children = append(node.Children, Node{Type: Comment, Literal: string(text)})
+			node.Children = append(node.Children, Node{Type: Comment, Literal: string(text), Position: st.span(s1)})
 			return true
 		}
 		return false
@@ -419,14 +762,11 @@ func parseBlockHTML(st *ParserState, node *Node) bool {
 		return false
 	}
 	// FIXME: Dirty hack with knowledge about inline/block html elements
-	if st.startsWith("<link ") {
+	if st.startsWith("<link ") || st.startsWith("<http") {
 		return false
 	}
-	st1 := InlineParserState{
-		source:   st.source,
-		children: []Node{},
-		text:     []byte{},
-	}
+	s1 := st.source
+	st1 := newInlineParserState(st.source, st.point(), st.origin.Column)
 	st1.parseHtml()
 	n1 := Node{}
 	if len(st1.children) > 0 {
@@ -434,7 +774,7 @@ func parseBlockHTML(st *ParserState, node *Node) bool {
 		n1 = st1.children[0]
 	} else {
 		line := st.consumeLine()
-		n1 = Node{Type: Text, Literal: string(line)}
+		n1 = Node{Type: Text, Literal: string(line), Position: st.span(s1)}
 	}
 	node.Children = append(node.Children, n1)
 	return true
@@ -444,6 +784,7 @@ func parseCodeFence(st *ParserState, node *Node) bool {
 	if !st.startsWith("```") {
 		return false
 	}
+	s0 := st.source
 	st.consumeN(len("```"))
 	language := st.consumeLine()
 	s1 := st.source
@@ -459,16 +800,55 @@ func parseCodeFence(st *ParserState, node *Node) bool {
 		Type:       CodeFence,
 		Attributes: map[string]string{},
 		Literal:    string(block),
+		Position:   st.span(s0),
 	}
 	if len(language) > 0 {
-		n1.Attributes["lang"] = string(language)
+		parseCodeFenceInfo(string(language), &n1)
 	}
 	node.Children = append(node.Children, n1)
 	return true
 }
 
+// codeFenceOptions are known options of fence info line, i.e.
+// ```python title="main.py" linenums="1" hl_lines="2 3"
+var codeFenceOptions = []string{"title", "linenums", "hl_lines"}
+
+var codeFenceOptionRegexp = regexp.MustCompile(`^([a-z_]+)="([^"]*)"\s*`)
+
+// parseCodeFenceInfo splits info line into language and options. Info
+// with unknown options is kept in lang as is, so it is written back intact.
+func parseCodeFenceInfo(info string, n1 *Node) {
+	fields := strings.SplitN(info, " ", 2)
+	options := map[string]string{}
+	if len(fields) > 1 {
+		rest := strings.TrimSpace(fields[1])
+		for len(rest) > 0 {
+			m := codeFenceOptionRegexp.FindStringSubmatch(rest)
+			if m == nil || !slices.Contains(codeFenceOptions, m[1]) {
+				n1.Attributes["lang"] = info
+				return
+			}
+			options[m[1]] = m[2]
+			rest = rest[len(m[0]):]
+		}
+	}
+	if len(fields[0]) > 0 {
+		n1.Attributes["lang"] = fields[0]
+	}
+	for k, v := range options {
+		n1.Attributes[k] = v
+	}
+}
+
 var yamlkvRegexp = regexp.MustCompile(`(\w+): (.+)`) //nolint:golint,lll
 
+// isMetaStart tells meta header from a thematic break in the first line:
+// header must be followed by key: value or by the closing ---.
+func isMetaStart(st *ParserState) bool {
+	next := st.peekLine(1)
+	return yamlkvRegexp.Match(next) || bytes.Equal(next, []byte("---"))
+}
+
 func parseMeta(st *ParserState, doc *Node) {
 	st.consumeLine()
 	for !st.eof() && !st.startsWith("---") && !st.startsWith("\n") {
@@ -487,6 +867,7 @@ func parseHeading(st *ParserState, node *Node) bool {
 	if !st.startsWith("#") {
 		return false
 	}
+	s1 := st.source
 	level := 0
 	for ; level < len(st.source) && st.source[level] == '#'; level++ {
 	}
@@ -497,6 +878,15 @@ func parseHeading(st *ParserState, node *Node) bool {
 	}
 
 	h := st.consumeLine()
+	parseHeadingText(h, &n1)
+	n1.Attributes["level"] = fmt.Sprintf("%d", level)
+	n1.Position = st.span(s1)
+	node.Children = append(node.Children, n1)
+	return true
+}
+
+// parseHeadingText fills heading title and id from "Title {#id}".
+func parseHeadingText(h []byte, n1 *Node) {
 	if a1 := bytes.Index(h, []byte{' ', '{'}); a1 > 0 {
 		a2 := bytes.Index(h[a1:], []byte{'}'})
 		if a2 < 0 {
@@ -508,14 +898,52 @@ func parseHeading(st *ParserState, node *Node) bool {
 			n1.Attributes["id"] = string(attrsLine[1:])
 		}
 	}
-
 	n1.Literal = string(h)
-	n1.Attributes["level"] = fmt.Sprintf("%d", level)
-	node.Children = append(node.Children, n1)
+}
+
+// isSetextUnderline detects === and --- lines under heading text.
+func isSetextUnderline(line []byte) bool {
+	line = bytes.TrimRight(line, " \t")
+	if len(line) == 0 || (line[0] != '=' && line[0] != '-') {
+		return false
+	}
+	for _, c := range line {
+		if c != line[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// isThematicBreak detects lines of three or more -, * or _ optionally
+// separated by spaces.
+func isThematicBreak(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || (line[0] != '-' && line[0] != '*' && line[0] != '_') {
+		return false
+	}
+	count := 0
+	for _, c := range line {
+		if c == line[0] {
+			count += 1
+		} else if c != ' ' && c != '\t' {
+			return false
+		}
+	}
+	return count >= 3
+}
+
+func parseThematicBreak(st *ParserState, node *Node) bool {
+	if !isThematicBreak(st.peekLine(0)) {
+		return false
+	}
+	s1 := st.source
+	st.consumeLine()
+	node.Children = append(node.Children, Node{Type: ThematicBreak, Position: st.span(s1)})
 	return true
 }
 
-var numberedListItemRe = regexp.MustCompile("^[\\d+]\\. ")
+var numberedListItemRe = regexp.MustCompile("^(\\d+)\\. ")
 
 func parseList(st *ParserState, node *Node) bool {
 	symbol := []byte{}
@@ -531,6 +959,7 @@ func parseList(st *ParserState, node *Node) bool {
 		return false
 	}
 
+	s1 := st.source
 	n1 := Node{
 		Type:       List,
 		Attributes: map[string]string{},
@@ -538,72 +967,262 @@ func parseList(st *ParserState, node *Node) bool {
 	}
 	if ordered {
 		n1.Attributes["ordered"] = "true"
+		start := string(numberedListItemRe.FindSubmatch(st.source)[1])
+		if start != "1" {
+			n1.Attributes["start"] = start
+		}
 	}
 	for !st.eof() && ((!ordered && st.startsWith(string(symbol))) ||
 		(ordered && numberedListItemRe.Match(st.source))) {
+		itemStart := st.source
+		if ordered {
+			symbol = numberedListItemRe.Find(st.source)
+		}
 		st.consumeN(len(symbol))
-		line := st.consumeLine()
-		if st.startsWith("\n") {
-			st.consumeLine()
+		lineStart := st.source
+		checked := ""
+		if st.startsWith("[ ] ") {
+			checked = "false"
+		} else if st.startsWith("[x] ") || st.startsWith("[X] ") {
+			checked = "true"
 		}
-		text := parseText(line)
+		if checked != "" {
+			st.consumeN(len("[ ] "))
+			lineStart = st.source
+		}
+		line := st.consumeLine()
+		text := parseText(line, st.pointAt(lineStart), st.origin.Column)
 		liContent := Node{
 			Type:     Paragraph,
 			Children: text,
+			Position: st.span(lineStart),
+		}
+		if st.startsWith("\n") {
+			st.consumeLine()
 		}
 		li := Node{
 			Type:     ListItem,
 			Children: []Node{liContent},
 		}
+		if checked != "" {
+			li.Attributes = map[string]string{"checked": checked}
+		}
 		nestedPrefix := "    "
 		if st.startsWith(nestedPrefix) {
-			var nested bytes.Buffer
-			for st.startsWith(nestedPrefix) {
-				l := bytes.TrimPrefix(st.consumeLine(), []byte(nestedPrefix))
-				nested.Write(l)
-				nested.WriteString("\n")
-			}
-			nestedDoc := MarkdownParse(nested.Bytes())
-			li.Children = append(li.Children, nestedDoc.Children...)
+			nestedStart := st.point()
+			nestedStart.Column += len(nestedPrefix)
+			nested := st.consumeIndented(nestedPrefix)
+			li.Children = append(li.Children, parseNested(nested, nestedStart)...)
 		}
+		li.Position = st.span(itemStart)
 		if st.startsWith("\n") {
 			st.consumeLine()
 		}
 		n1.Children = append(n1.Children, li)
 	}
+	n1.Position = st.span(s1)
 	node.Children = append(node.Children, n1)
 	return true
 }
 
-func parseAdmonition(st *ParserState, node *Node) bool {
-	if !st.startsWith("!!! ") {
+var linkDefinitionRegexp = regexp.MustCompile(`^\[([^\]^][^\]]*)\]:[ \t]+(\S+)(?:[ \t]+"([^"]*)")?[ \t]*$`)
+
+func parseLinkDefinition(st *ParserState, node *Node) bool {
+	m := linkDefinitionRegexp.FindSubmatch(st.peekLine(0))
+	if m == nil {
+		return false
+	}
+	s1 := st.source
+	st.consumeLine()
+	n1 := Node{
+		Type:       linkDefinition,
+		Attributes: map[string]string{"ref": string(m[1]), "url": string(m[2])},
+		Position:   st.span(s1),
+	}
+	if len(m[3]) > 0 {
+		n1.Attributes["title"] = string(m[3])
+	}
+	node.Children = append(node.Children, n1)
+	return true
+}
+
+var footnoteDefRegexp = regexp.MustCompile(`^\[\^([^\]\s]+)\]: `)
+
+// parseFootnoteDef reads [^id]: text with following lines indented by four
+// spaces, which may contain several paragraphs and other blocks.
+func parseFootnoteDef(st *ParserState, node *Node) bool {
+	m := footnoteDefRegexp.FindSubmatch(st.source)
+	if m == nil {
 		return false
 	}
-	st.consumeN(len("!!! "))
-	level := strings.TrimSpace(string(st.consumeLine()))
+	s1 := st.source
+	st.consumeN(len(m[0]))
+	bodyStart := st.point()
+	var body bytes.Buffer
+	body.Write(st.consumeLine())
+	body.WriteString("\n")
 	starter := "    "
-	var text bytes.Buffer
-	first := true
-	for st.startsWith(starter) {
-		line := st.consumeLine()[len(starter):]
-		if !first {
-			text.WriteString("\n")
-		}
-		text.Write(line)
-		first = false
+	body.Write(st.consumeIndented(starter))
+	bodyStart.Column = st.origin.Column + len(starter)
+	n1 := Node{
+		Type:       FootnoteDef,
+		Attributes: map[string]string{"id": string(m[1])},
+		Children:   parseNested(body.Bytes(), bodyStart),
+		Position:   st.span(s1),
+	}
+	node.Children = append(node.Children, n1)
+	return true
+}
+
+func parseBlockquote(st *ParserState, node *Node) bool {
+	if !st.startsWith(">") {
+		return false
+	}
+	s1 := st.source
+	bodyStart := st.point()
+	bodyStart.Column += len("> ")
+	var body bytes.Buffer
+	for st.startsWith(">") {
+		line := st.consumeLine()[1:]
+		line = bytes.TrimPrefix(line, []byte{' '})
+		body.Write(line)
+		body.WriteString("\n")
 	}
-	children := parseText(text.Bytes())
+	n1 := Node{
+		Type:     Blockquote,
+		Children: parseNested(body.Bytes(), bodyStart),
+		Position: st.span(s1),
+	}
+	node.Children = append(node.Children, n1)
+	return true
+}
+
+var admonitionRegexp = regexp.MustCompile(`^(!!!|\?\?\?\+?) +([^"\s][^"]*?)(?: +"(.*)")?[ \t]*\r?$`)
+
+// parseAdmonition reads !!! level "Title" with indented blocks. ??? makes
+// it collapsible, ???+ expanded at start.
+func parseAdmonition(st *ParserState, node *Node) bool {
+	line := st.peekLine(0)
+	m := admonitionRegexp.FindSubmatchIndex(line)
+	if m == nil {
+		return false
+	}
+	s1 := st.source
+	st.consumeLine()
+	attrs := map[string]string{"level": string(line[m[4]:m[5]])}
+	// empty title "" hides the title
+	if m[6] >= 0 {
+		attrs["title"] = string(line[m[6]:m[7]])
+	}
+	switch string(line[m[2]:m[3]]) {
+	case "???":
+		attrs["collapsible"] = "closed"
+	case "???+":
+		attrs["collapsible"] = "open"
+	}
+	starter := "    "
+	bodyStart := st.point()
+	bodyStart.Column += len(starter)
 	n1 := Node{
 		Type:       Admonition,
-		Attributes: map[string]string{"level": level},
-		Children:   children,
+		Attributes: attrs,
+		Children:   parseNested(st.consumeIndented(starter), bodyStart),
+		Position:   st.span(s1),
+	}
+	node.Children = append(node.Children, n1)
+	return true
+}
+
+var tabRegexp = regexp.MustCompile(`^===([+!]*) "(.*)"[ \t]*\r?$`)
+
+// parseTabs reads consecutive content tabs === "Title" with indented
+// blocks into one group. ===! starts new group, ===+ selects the tab.
+func parseTabs(st *ParserState, node *Node) bool {
+	if tabRegexp.Find(st.peekLine(0)) == nil {
+		return false
+	}
+	s1 := st.source
+	n1 := Node{Type: Tabs, Children: []Node{}}
+	for {
+		m := tabRegexp.FindSubmatch(st.peekLine(0))
+		if m == nil || (len(n1.Children) > 0 && bytes.Contains(m[1], []byte{'!'})) {
+			break
+		}
+		s2 := st.source
+		st.consumeLine()
+		starter := "    "
+		bodyStart := st.point()
+		bodyStart.Column += len(starter)
+		tab := Node{
+			Type:       Tab,
+			Attributes: map[string]string{"title": string(m[2])},
+			Children:   parseNested(st.consumeIndented(starter), bodyStart),
+			Position:   st.span(s2),
+		}
+		if bytes.Contains(m[1], []byte{'+'}) {
+			tab.Attributes["selected"] = "true"
+		}
+		n1.Children = append(n1.Children, tab)
+		// tabs of the group may be separated by empty lines
+		rest := st.source
+		for st.startsWith("\n") {
+			st.consumeLine()
+		}
+		if tabRegexp.Find(st.peekLine(0)) == nil {
+			st.source = rest
+			break
+		}
 	}
+	n1.Position = st.span(s1)
 	node.Children = append(node.Children, n1)
 	return true
 }
 
 type ParserState struct {
 	source []byte
+	// whole text being parsed and offsets of its lines, used to find
+	// position of nodes
+	full  []byte
+	lines []int
+	// place of full in the document
+	origin Point
+}
+
+func newParserState(source []byte, origin Point) *ParserState {
+	st := &ParserState{
+		source: source,
+		full:   source,
+		lines:  []int{0},
+		origin: origin,
+	}
+	for i, c := range source {
+		if c == '\n' {
+			st.lines = append(st.lines, i+1)
+		}
+	}
+	return st
+}
+
+// pointAt returns place in the document where rest of the source begins.
+func (st *ParserState) pointAt(rest []byte) Point {
+	offset := len(st.full) - len(rest)
+	i := sort.Search(len(st.lines), func(i int) bool { return st.lines[i] > offset }) - 1
+	return Point{
+		Line:   st.origin.Line + i,
+		Column: st.origin.Column + utf8.RuneCount(st.full[st.lines[i]:offset]),
+	}
+}
+
+func (st *ParserState) point() Point {
+	return st.pointAt(st.source)
+}
+
+// span returns position of everything consumed since start without
+// trailing line breaks.
+func (st *ParserState) span(start []byte) *Position {
+	consumed := start[:len(start)-len(st.source)]
+	trimmed := bytes.TrimRight(consumed, "\r\n")
+	return &Position{Start: st.pointAt(start), End: st.pointAt(start[len(trimmed):])}
 }
 
 func (st *ParserState) eof() bool {
@@ -635,6 +1254,33 @@ func (st *ParserState) consumeLine() []byte {
 	return line
 }
 
+// consumeIndented reads block of lines indented with prefix and returns
+// them without prefix. Empty lines inside the block are kept.
+func (st *ParserState) consumeIndented(prefix string) []byte {
+	var block bytes.Buffer
+	for !st.eof() {
+		if st.startsWith(prefix) {
+			block.Write(st.consumeLine()[len(prefix):])
+			block.WriteString("\n")
+		} else if st.startsWith("\n") && bytes.HasPrefix(st.peekLine(1), []byte(prefix)) {
+			st.consumeLine()
+			block.WriteString("\n")
+		} else {
+			break
+		}
+	}
+	return block.Bytes()
+}
+
+// peekLine returns n-th line from the current one without consuming it.
+func (st *ParserState) peekLine(n int) []byte {
+	st1 := *st
+	for i := 0; i < n; i++ {
+		st1.consumeLine()
+	}
+	return st1.consumeLine()
+}
+
 func (st *ParserState) consumeN(n int) []byte {
 	if n > len(st.source) {
 		n = len(st.source)
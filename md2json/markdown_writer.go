@@ -8,28 +8,75 @@ import (
 	"strconv"
 )
 
-func Json2Md(input string, output string) error {
+// WriteOptions tune the markdown produced from the document.
+type WriteOptions struct {
+	// ReferenceLinks keeps links parsed from [text][ref] in reference style
+	// and writes their definitions in the end of the document.
+	ReferenceLinks bool
+}
+
+func Json2Md(input string, output string, opts WriteOptions) error {
 	doc, err := ReadJson(input)
 	if err != nil {
 		return err
 	}
-	text := WriteDocument(&doc)
+	text := WriteDocumentOptions(&doc, opts)
 	err = os.WriteFile(output, text, os.ModePerm)
 	return err
 }
 
 func WriteDocument(n *Node) []byte {
+	return WriteDocumentOptions(n, WriteOptions{})
+}
+
+func WriteDocumentOptions(n *Node, opts WriteOptions) []byte {
+	var text bytes.Buffer
+	meta := writeDocumentMeta(n)
+	var doc Node
+	if opts.ReferenceLinks {
+		doc = *n
+	} else {
+		doc = dropReferenceLinks(n)
+	}
+	body, footnotes := splitFootnotes(&doc)
+	blocks := writeBlocks(&body)
+	text.Write(meta)
+	if len(meta) > 0 && len(blocks) > 0 {
+		text.WriteByte('\n')
+	}
+	text.Write(blocks)
+	if definitions := writeLinkDefinitions(&body); len(definitions) > 0 {
+		if len(text.Bytes()) > 0 {
+			text.WriteByte('\n')
+		}
+		text.Write(definitions)
+	}
+	for _, fn := range footnotes {
+		if len(text.Bytes()) > 0 {
+			text.WriteByte('\n')
+		}
+		text.Write(writeFootnoteDef(&fn))
+	}
+	return text.Bytes()
+}
+
+// writeBlocks writes block children of n separated by empty lines.
+func writeBlocks(n *Node) []byte {
 	var text bytes.Buffer
-	text.Write(writeDocumentMeta(n))
 	if n.Children != nil {
-		for _, ch := range n.Children {
+		for i, ch := range n.Children {
 			if len(text.Bytes()) > 0 {
 				if !bytes.HasSuffix(text.Bytes(), []byte{'\n'}) {
 					text.WriteByte('\n')
 				}
 				text.WriteByte('\n')
 			}
-			text.Write(writeNode(&ch))
+			block := writeNode(&ch)
+			if ch.Type == Tabs && i > 0 && n.Children[i-1].Type == Tabs {
+				// tabs right after other tabs start new group
+				block = append([]byte("===!"), block[len("==="):]...)
+			}
+			text.Write(block)
 		}
 	}
 	return text.Bytes()
@@ -73,6 +120,8 @@ func writeNode(n *Node) []byte {
 		return writeEmphasis(n)
 	case Bold:
 		return writeBold(n)
+	case Strikethrough:
+		return writeStrikethrough(n)
 	case Code:
 		return writeCode(n)
 	case Heading:
@@ -89,6 +138,18 @@ func writeNode(n *Node) []byte {
 		return writeHTML(n)
 	case Table:
 		return writeTable(n)
+	case Blockquote:
+		return writeBlockquote(n)
+	case FootnoteRef:
+		return []byte("[^" + n.Attributes["id"] + "]")
+	case FootnoteDef:
+		return writeFootnoteDef(n)
+	case Tabs:
+		return writeTabs(n)
+	case ThematicBreak:
+		return []byte("---\n")
+	case LineBreak:
+		return []byte("  \n")
 	default:
 		fmt.Println("Type", n.Type)
 	}
@@ -175,6 +236,14 @@ func writeBold(n *Node) []byte {
 	return text.Bytes()
 }
 
+func writeStrikethrough(n *Node) []byte {
+	var text bytes.Buffer
+	text.WriteString("~~")
+	text.Write(writeInliner(n))
+	text.WriteString("~~")
+	return text.Bytes()
+}
+
 func writeCode(n *Node) []byte {
 	var text bytes.Buffer
 	text.WriteString("`")
@@ -197,19 +266,44 @@ func writeImage(n *Node) []byte {
 func writeLink(n *Node) []byte {
 	var text bytes.Buffer
 	src, _ := n.Attributes["href"]
+	switch n.Attributes["autolink"] {
+	case "bare":
+		return []byte(src)
+	case "angle":
+		return []byte("<" + src + ">")
+	}
 	text.WriteString("[")
 	text.WriteString(n.Literal)
+	ref, ok := n.Attributes["ref"]
+	if ok {
+		text.WriteString("][")
+		if ref != n.Literal {
+			text.WriteString(ref)
+		}
+		text.WriteString("]")
+		return text.Bytes()
+	}
 	text.WriteString("](")
-	text.WriteString(src)
-	anchor, ok2 := n.Attributes["anchor"]
-	if ok2 {
-		text.WriteString("#")
-		text.WriteString(anchor)
+	text.WriteString(linkUrl(n))
+	if title, ok := n.Attributes["title"]; ok {
+		text.WriteString(" \"")
+		text.WriteString(title)
+		text.WriteString("\"")
 	}
 	text.WriteString(")")
 	return text.Bytes()
 }
 
+// linkUrl joins href and anchor of the link back.
+func linkUrl(n *Node) string {
+	src, _ := n.Attributes["href"]
+	anchor, ok := n.Attributes["anchor"]
+	if ok {
+		return src + "#" + anchor
+	}
+	return src
+}
+
 func writeCodeFence(n *Node) []byte {
 	var text bytes.Buffer
 	text.WriteString("```")
@@ -218,6 +312,12 @@ func writeCodeFence(n *Node) []byte {
 		if ok {
 			text.WriteString(lang)
 		}
+		for _, k := range codeFenceOptions {
+			v, ok := n.Attributes[k]
+			if ok {
+				text.WriteString(fmt.Sprintf(" %s=\"%s\"", k, v))
+			}
+		}
 	}
 	text.WriteString("\n")
 	text.WriteString(n.Literal)
@@ -228,14 +328,19 @@ func writeCodeFence(n *Node) []byte {
 func writeList(n *Node) []byte {
 	var text bytes.Buffer
 	ordered := false
+	start := 1
 	if n.Children != nil {
 		_, ok := n.Attributes["ordered"]
 		if ok {
 			ordered = true
 		}
+		start0, ok := n.Attributes["start"]
+		if ok {
+			start, _ = strconv.Atoi(start0)
+		}
 	}
 	for i, ch := range n.Children {
-		j := i + 1
+		j := start + i
 		if !ordered {
 			j = -1
 		}
@@ -254,6 +359,12 @@ func writeListItem(i int, n *Node) []byte {
 	} else {
 		text.WriteString(fmt.Sprintf("%d. ", i))
 	}
+	switch n.Attributes["checked"] {
+	case "true":
+		text.WriteString("[x] ")
+	case "false":
+		text.WriteString("[ ] ")
+	}
 	if len(n.Children) == 0 {
 		text.WriteString("\n")
 		return text.Bytes()
@@ -263,18 +374,14 @@ func writeListItem(i int, n *Node) []byte {
 		return text.Bytes()
 	}
 	text.WriteString("\n")
-	var nested bytes.Buffer
-	for _, ch := range n.Children[1:] {
-		nested.Write(writeNode(&ch))
-	}
-	nestedBytes := nested.Bytes()
-	if nestedBytes[len(nestedBytes)-1] == '\n' {
-		nestedBytes = nestedBytes[:len(nestedBytes)-1]
-	}
+	nested := Node{Children: n.Children[1:]}
+	nestedBytes := bytes.TrimSuffix(writeBlocks(&nested), []byte{'\n'})
 	rows := bytes.Split(nestedBytes, []byte("\n"))
 	for _, r := range rows {
-		text.WriteString("    ")
-		text.Write(r)
+		if len(r) > 0 {
+			text.WriteString("    ")
+			text.Write(r)
+		}
 		text.WriteString("\n")
 	}
 	return text.Bytes()
@@ -282,20 +389,86 @@ func writeListItem(i int, n *Node) []byte {
 
 func writeAdmonition(n *Node) []byte {
 	var text bytes.Buffer
-	level, _ := n.Attributes["level"]
-	text.WriteString("!!! ")
-	text.WriteString(level)
+	switch n.Attributes["collapsible"] {
+	case "closed":
+		text.WriteString("??? ")
+	case "open":
+		text.WriteString("???+ ")
+	default:
+		text.WriteString("!!! ")
+	}
+	text.WriteString(n.Attributes["level"])
+	if title, ok := n.Attributes["title"]; ok {
+		text.WriteString(fmt.Sprintf(" \"%s\"", title))
+	}
 	text.WriteString("\n")
-	inner := writeChildren(n)
+	inner := bytes.TrimSuffix(writeBlocks(n), []byte{'\n'})
+	for _, r := range bytes.Split(inner, []byte{'\n'}) {
+		if len(r) > 0 {
+			text.WriteString("    ")
+			text.Write(r)
+		}
+		text.WriteByte('\n')
+	}
+	return text.Bytes()
+}
+
+func writeTabs(n *Node) []byte {
+	var text bytes.Buffer
+	for i, tab := range n.Children {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		text.WriteString("===")
+		if tab.Attributes["selected"] == "true" {
+			text.WriteString("+")
+		}
+		text.WriteString(fmt.Sprintf(" \"%s\"\n\n", tab.Attributes["title"]))
+		inner := bytes.TrimSuffix(writeBlocks(&tab), []byte{'\n'})
+		for _, r := range bytes.Split(inner, []byte{'\n'}) {
+			if len(r) > 0 {
+				text.WriteString("    ")
+				text.Write(r)
+			}
+			text.WriteString("\n")
+		}
+	}
+	return text.Bytes()
+}
+
+func writeFootnoteDef(n *Node) []byte {
+	var text bytes.Buffer
+	text.WriteString("[^")
+	text.WriteString(n.Attributes["id"])
+	text.WriteString("]: ")
+	inner := bytes.TrimSuffix(writeBlocks(n), []byte{'\n'})
 	rows := bytes.Split(inner, []byte{'\n'})
-	for _, r := range rows {
-		text.WriteString("    ")
+	for i, r := range rows {
+		if i > 0 && len(r) > 0 {
+			text.WriteString("    ")
+		}
 		text.Write(r)
 		text.WriteByte('\n')
 	}
 	return text.Bytes()
 }
 
+func writeBlockquote(n *Node) []byte {
+	var text bytes.Buffer
+	inner := bytes.TrimSuffix(writeBlocks(n), []byte{'\n'})
+	rows := bytes.Split(inner, []byte{'\n'})
+	for _, r := range rows {
+		if len(r) > 0 {
+			text.WriteString("> ")
+			text.Write(r)
+		} else {
+			text.WriteString(">")
+		}
+		text.WriteByte('\n')
+	}
+	return text.Bytes()
+}
+
 func writeHTML(n *Node) []byte {
 	var text bytes.Buffer
 	block := false
@@ -318,15 +491,20 @@ func writeHTML(n *Node) []byte {
 		text.WriteString(">")
 		return text.Bytes()
 	}
-	for k, v := range n.Attributes {
+	keys := make([]string, 0, len(n.Attributes))
+	for k := range n.Attributes {
 		if k != "tag" {
-			text.WriteString(" ")
-			text.WriteString(k)
-			text.WriteString("=\"")
-			text.WriteString(v)
-			text.WriteString("\"")
+			keys = append(keys, k)
 		}
 	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		text.WriteString(" ")
+		text.WriteString(k)
+		text.WriteString("=\"")
+		text.WriteString(n.Attributes[k])
+		text.WriteString("\"")
+	}
 	if len(n.Literal) > 0 || (n.Children != nil && len(n.Children) > 0) {
 		text.WriteString(">")
 		if block {
@@ -353,15 +531,36 @@ func writeTable(node *Node) []byte {
 	var text bytes.Buffer
 	header := node.Children[0]
 	body := node.Children[1]
+	if len(node.Attributes) > 0 {
+		keys := make([]string, 0, len(node.Attributes))
+		for k := range node.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		text.WriteString("<!-- table")
+		for _, k := range keys {
+			text.WriteString(fmt.Sprintf(" %s=\"%s\"", k, node.Attributes[k]))
+		}
+		text.WriteString(" -->\n")
+	}
 	text.WriteString("|")
 
 	var second bytes.Buffer
 	second.WriteString("|")
 	for _, h := range header.Children {
 		text.WriteString(" ")
-		text.Write(writeNode(&h))
+		text.Write(writeTableCell(&h))
 		text.WriteString(" |")
-		second.WriteString("---|")
+		switch h.Attributes["align"] {
+		case "left":
+			second.WriteString(":---|")
+		case "center":
+			second.WriteString(":---:|")
+		case "right":
+			second.WriteString("---:|")
+		default:
+			second.WriteString("---|")
+		}
 	}
 	text.WriteString("\n")
 	text.Write(second.Bytes())
@@ -370,10 +569,33 @@ func writeTable(node *Node) []byte {
 		text.WriteString("|")
 		for _, cell := range row.Children {
 			text.WriteString(" ")
-			text.Write(writeChildren(&cell))
+			text.Write(writeTableCell(&cell))
 			text.WriteString(" |")
 		}
 		text.WriteString("\n")
 	}
 	return text.Bytes()
 }
+
+// writeTableCell escapes pipes in cell content except inside code spans.
+// Header cells of old documents are plain Text nodes.
+func writeTableCell(n *Node) []byte {
+	var inner []byte
+	if n.Type == Text {
+		inner = writeText(n)
+	} else {
+		inner = writeChildren(n)
+	}
+	var text bytes.Buffer
+	inCode := false
+	for _, c := range inner {
+		if c == '`' {
+			inCode = !inCode
+		}
+		if c == '|' && !inCode {
+			text.WriteByte('\\')
+		}
+		text.WriteByte(c)
+	}
+	return text.Bytes()
+}
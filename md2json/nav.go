@@ -0,0 +1,113 @@
+package md2json
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// navItem is a page or a section of mkdocs nav. Pages may have title
+// given in nav, sections have title and children.
+type navItem struct {
+	title    string
+	page     string
+	children []navItem
+}
+
+// parseNav reads nav of mkdocs config. Links to external sites and
+// other non-markdown items are skipped.
+func parseNav(nav interface{}) ([]navItem, error) {
+	items, ok := nav.([]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("nav must be a list, not %v", reflect.TypeOf(nav)))
+	}
+	result := []navItem{}
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			if strings.HasSuffix(v, ".md") {
+				result = append(result, navItem{page: v})
+			}
+		case map[string]interface{}:
+			for title, v1 := range v {
+				switch v2 := v1.(type) {
+				case string:
+					if strings.HasSuffix(v2, ".md") {
+						result = append(result, navItem{title: title, page: v2})
+					}
+				case []interface{}:
+					children, err := parseNav(v2)
+					if err != nil {
+						return nil, err
+					}
+					result = append(result, navItem{title: title, children: children})
+				default:
+					return nil, errors.New(fmt.Sprintf("Unknown nav item '%s' -> %v", title, v1))
+				}
+			}
+		default:
+			return nil, errors.New(fmt.Sprintf("Unknown nav item %v", item))
+		}
+	}
+	return result, nil
+}
+
+// navPages lists pages of nav in reading order.
+func navPages(items []navItem) []navItem {
+	pages := []navItem{}
+	for _, item := range items {
+		if item.page != "" {
+			pages = append(pages, item)
+		}
+		pages = append(pages, navPages(item.children)...)
+	}
+	return pages
+}
+
+// readMkdocsNav reads mkdocs config with its nav and the directory of
+// documents.
+func readMkdocsNav(mkdocsPath string) (map[string]interface{}, string, []navItem, error) {
+	mkdocs, err := YamlParse(mkdocsPath)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	docsDir, ok := mkdocs["docs_dir"]
+	if !ok {
+		return nil, "", nil, errors.New(fmt.Sprintf("No docs_dir in mkdocs config %s", mkdocsPath))
+	}
+	nav, ok := mkdocs["nav"]
+	if !ok {
+		return nil, "", nil, errors.New(fmt.Sprintf("No nav in mkdocs config %s", mkdocsPath))
+	}
+	items, err := parseNav(nav)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	inDir := filepath.Join(filepath.Dir(mkdocsPath), fmt.Sprintf("%v", docsDir))
+	return mkdocs, inDir, items, nil
+}
+
+// readNavPages reads documents of nav pages and their titles: title
+// given in nav, heading of the page or its file name.
+func readNavPages(inDir string, pages []navItem) (map[string]Node, map[string]string, error) {
+	docs := map[string]Node{}
+	titles := map[string]string{}
+	for _, p := range pages {
+		doc, err := ReadJson(filepath.Join(inDir, p.page))
+		if err != nil {
+			return nil, nil, err
+		}
+		docs[p.page] = doc
+		title := p.title
+		if title == "" {
+			title, _, _ = doc.Heading()
+		}
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(p.page), ".md")
+		}
+		titles[p.page] = title
+	}
+	return docs, titles, nil
+}
@@ -0,0 +1,714 @@
+package md2json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pandocApiVersion is version of pandoc AST the bridge is written for
+var pandocApiVersion = []interface{}{1, 23, 1}
+
+// pandocExporter converts nodes to pandoc AST and collects what pandoc
+// has no constructor for.
+type pandocExporter struct {
+	source      string
+	unsupported []string
+}
+
+func pandocEl(t string, c interface{}) map[string]interface{} {
+	if c == nil {
+		return map[string]interface{}{"t": t}
+	}
+	return map[string]interface{}{"t": t, "c": c}
+}
+
+func pandocAttr(id string, classes []string, kv [][]string) []interface{} {
+	cs := []interface{}{}
+	for _, c := range classes {
+		cs = append(cs, c)
+	}
+	kvs := []interface{}{}
+	for _, p := range kv {
+		kvs = append(kvs, []interface{}{p[0], p[1]})
+	}
+	return []interface{}{id, cs, kvs}
+}
+
+// pandocStr splits text into Str, Space and SoftBreak like pandoc reader.
+func pandocStr(text string) []interface{} {
+	inlines := []interface{}{}
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			inlines = append(inlines, pandocEl("Str", word.String()))
+			word.Reset()
+		}
+	}
+	for _, c := range text {
+		switch c {
+		case ' ', '\t':
+			flush()
+			if len(inlines) == 0 || inlines[len(inlines)-1].(map[string]interface{})["t"] == "Str" {
+				inlines = append(inlines, pandocEl("Space", nil))
+			}
+		case '\n':
+			flush()
+			if len(inlines) > 0 && inlines[len(inlines)-1].(map[string]interface{})["t"] == "Space" {
+				inlines = inlines[:len(inlines)-1]
+			}
+			inlines = append(inlines, pandocEl("SoftBreak", nil))
+		default:
+			word.WriteRune(c)
+		}
+	}
+	flush()
+	return inlines
+}
+
+func (e *pandocExporter) fail(n *Node, what string) {
+	e.unsupported = append(e.unsupported, fmt.Sprintf("%s at %s", what, n.Location(e.source)))
+}
+
+func (e *pandocExporter) blocks(n *Node) []interface{} {
+	blocks := []interface{}{}
+	for i := range n.Children {
+		b := e.block(&n.Children[i])
+		if b != nil {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+func (e *pandocExporter) inlines(n *Node) []interface{} {
+	inlines := []interface{}{}
+	for i := range n.Children {
+		inlines = append(inlines, e.inline(&n.Children[i])...)
+	}
+	return inlines
+}
+
+// inliner converts content of emphasis-like node, which is either plain
+// literal or nested inline nodes.
+func (e *pandocExporter) inliner(n *Node) []interface{} {
+	if n.Children == nil {
+		return pandocStr(n.Literal)
+	}
+	return e.inlines(n)
+}
+
+func (e *pandocExporter) block(n *Node) interface{} {
+	switch n.Type {
+	case Paragraph:
+		return pandocEl("Para", e.inlines(n))
+	case Text:
+		// text of unfinished html at block level
+		return pandocEl("Plain", pandocStr(n.Literal))
+	case Heading:
+		level, err := strconv.Atoi(n.Attributes["level"])
+		if err != nil {
+			level = 3
+		}
+		id, ok := n.Attributes["id"]
+		if !ok {
+			id = htmlId(n.Literal)
+		}
+		return pandocEl("Header", []interface{}{level, pandocAttr(id, nil, nil), pandocStr(n.Literal)})
+	case List:
+		items := []interface{}{}
+		for i := range n.Children {
+			item := e.blocks(&n.Children[i])
+			// lists of markdown are tight, pandoc writes them with Plain
+			for j := range item {
+				if b := item[j].(map[string]interface{}); b["t"] == "Para" {
+					item[j] = pandocEl("Plain", b["c"])
+				}
+			}
+			box := ""
+			switch n.Children[i].Attributes["checked"] {
+			case "true":
+				box = "☒"
+			case "false":
+				box = "☐"
+			}
+			if box != "" && len(item) > 0 && item[0].(map[string]interface{})["t"] == "Plain" {
+				plain := item[0].(map[string]interface{})
+				inlines := append([]interface{}{pandocEl("Str", box), pandocEl("Space", nil)}, plain["c"].([]interface{})...)
+				item[0] = pandocEl("Plain", inlines)
+			}
+			items = append(items, item)
+		}
+		if _, ordered := n.Attributes["ordered"]; ordered {
+			start, err := strconv.Atoi(n.Attributes["start"])
+			if err != nil {
+				start = 1
+			}
+			listAttrs := []interface{}{start, pandocEl("Decimal", nil), pandocEl("Period", nil)}
+			return pandocEl("OrderedList", []interface{}{listAttrs, items})
+		}
+		return pandocEl("BulletList", items)
+	case Admonition:
+		level, _ := n.Attributes["level"]
+		kv := [][]string{}
+		for _, k := range []string{"title", "collapsible"} {
+			if v, ok := n.Attributes[k]; ok {
+				kv = append(kv, []string{k, v})
+			}
+		}
+		return pandocEl("Div", []interface{}{pandocAttr("", []string{"admonition", level}, kv), e.blocks(n)})
+	case Tabs:
+		tabs := []interface{}{}
+		for i := range n.Children {
+			tab := &n.Children[i]
+			kv := [][]string{}
+			for _, k := range []string{"title", "selected"} {
+				if v, ok := tab.Attributes[k]; ok {
+					kv = append(kv, []string{k, v})
+				}
+			}
+			tabs = append(tabs, pandocEl("Div", []interface{}{pandocAttr("", []string{"tabbed"}, kv), e.blocks(tab)}))
+		}
+		return pandocEl("Div", []interface{}{pandocAttr("", []string{"tabbed-set"}, nil), tabs})
+	case CodeFence:
+		classes := []string{}
+		lang := strings.SplitN(n.Attributes["lang"], " ", 2)[0]
+		if lang != "" {
+			classes = append(classes, lang)
+		}
+		kv := [][]string{}
+		for _, k := range codeFenceOptions {
+			v, ok := n.Attributes[k]
+			if ok {
+				kv = append(kv, []string{k, v})
+			}
+		}
+		return pandocEl("CodeBlock", []interface{}{pandocAttr("", classes, kv), strings.TrimSuffix(n.Literal, "\n")})
+	case Table:
+		return e.table(n)
+	case Blockquote:
+		return pandocEl("BlockQuote", e.blocks(n))
+	case ThematicBreak:
+		return pandocEl("HorizontalRule", nil)
+	case HTML:
+		return pandocEl("RawBlock", []interface{}{"html", strings.TrimSuffix(string(writeHTML(n)), "\n")})
+	case Comment:
+		return pandocEl("RawBlock", []interface{}{"html", strings.TrimSuffix(string(writeComment(n)), "\n")})
+	case FootnoteDef:
+		// definitions go into Note inlines of references
+		return nil
+	}
+	e.fail(n, fmt.Sprintf("block %s", n.Type))
+	return nil
+}
+
+func (e *pandocExporter) inline(n *Node) []interface{} {
+	switch n.Type {
+	case Text:
+		return pandocStr(n.Literal)
+	case Emphasis:
+		return []interface{}{pandocEl("Emph", e.inliner(n))}
+	case Bold:
+		return []interface{}{pandocEl("Strong", e.inliner(n))}
+	case Strikethrough:
+		return []interface{}{pandocEl("Strikeout", e.inliner(n))}
+	case Code:
+		return []interface{}{pandocEl("Code", []interface{}{pandocAttr("", nil, nil), n.Literal})}
+	case LineBreak:
+		return []interface{}{pandocEl("LineBreak", nil)}
+	case Link:
+		classes := []string{}
+		text := n.Literal
+		if _, ok := n.Attributes["autolink"]; ok {
+			classes = append(classes, "uri")
+			text = n.Attributes["href"]
+		}
+		target := []interface{}{linkUrl(n), n.Attributes["title"]}
+		return []interface{}{pandocEl("Link", []interface{}{pandocAttr("", classes, nil), pandocStr(text), target})}
+	case Image:
+		target := []interface{}{n.Attributes["src"], ""}
+		return []interface{}{pandocEl("Image", []interface{}{pandocAttr("", nil, nil), pandocStr(n.Literal), target})}
+	case FootnoteRef:
+		return []interface{}{pandocEl("Note", e.blocks(n))}
+	case HTML:
+		return []interface{}{pandocEl("RawInline", []interface{}{"html", string(writeHTML(n))})}
+	case Comment:
+		return []interface{}{pandocEl("RawInline", []interface{}{"html", string(writeComment(n))})}
+	}
+	e.fail(n, fmt.Sprintf("inline %s", n.Type))
+	return []interface{}{}
+}
+
+func pandocAlign(align string) interface{} {
+	switch align {
+	case "left":
+		return pandocEl("AlignLeft", nil)
+	case "center":
+		return pandocEl("AlignCenter", nil)
+	case "right":
+		return pandocEl("AlignRight", nil)
+	}
+	return pandocEl("AlignDefault", nil)
+}
+
+func (e *pandocExporter) table(n *Node) interface{} {
+	header := n.Children[0]
+	body := n.Children[1]
+	colspecs := []interface{}{}
+	cells := []interface{}{}
+	cell := func(align string, inlines []interface{}) interface{} {
+		return []interface{}{pandocAttr("", nil, nil), pandocAlign(align), 1, 1, []interface{}{pandocEl("Plain", inlines)}}
+	}
+	for i := range header.Children {
+		th := &header.Children[i]
+		align := th.Attributes["align"]
+		colspecs = append(colspecs, []interface{}{pandocAlign(align), pandocEl("ColWidthDefault", nil)})
+		inlines := pandocStr(th.Literal)
+		if th.Type != Text {
+			inlines = e.inlines(th)
+		}
+		cells = append(cells, cell("", inlines))
+	}
+	head := []interface{}{pandocAttr("", nil, nil), []interface{}{[]interface{}{pandocAttr("", nil, nil), cells}}}
+	rows := []interface{}{}
+	for _, row := range body.Children {
+		cells := []interface{}{}
+		for i := range row.Children {
+			cells = append(cells, cell("", e.inlines(&row.Children[i])))
+		}
+		rows = append(rows, []interface{}{pandocAttr("", nil, nil), cells})
+	}
+	bodies := []interface{}{[]interface{}{pandocAttr("", nil, nil), 0, []interface{}{}, rows}}
+	foot := []interface{}{pandocAttr("", nil, nil), []interface{}{}}
+	caption := []interface{}{nil, []interface{}{}}
+	return pandocEl("Table", []interface{}{pandocAttr("", nil, nil), caption, colspecs, head, bodies, foot})
+}
+
+// Pandoc converts the document to pandoc JSON AST. Kinds pandoc has no
+// constructor for are reported in error with their places.
+func Pandoc(n *Node, path string) ([]byte, error) {
+	doc, footnotes := splitFootnotes(n)
+	attachFootnotes(&doc, footnotes)
+	e := &pandocExporter{source: n.Source(path)}
+	meta := map[string]interface{}{}
+	for k, v := range n.Attributes {
+		meta[k] = pandocEl("MetaString", v)
+	}
+	ast := map[string]interface{}{
+		"pandoc-api-version": pandocApiVersion,
+		"meta":               meta,
+		"blocks":             e.blocks(&doc),
+	}
+	if len(e.unsupported) > 0 {
+		return nil, errors.New(fmt.Sprintf("Not supported by pandoc: %s", strings.Join(e.unsupported, ", ")))
+	}
+	return json.Marshal(ast)
+}
+
+// pandocImporter converts pandoc AST to nodes and collects constructors
+// marktome has no kind for.
+type pandocImporter struct {
+	notes       []Node
+	unsupported map[string]bool
+}
+
+// pandocValue is a pandoc element: constructor name and its content.
+func pandocValue(v interface{}) (string, interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	t, _ := m["t"].(string)
+	return t, m["c"]
+}
+
+func pandocList(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func pandocString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func pandocInt(v interface{}) int {
+	f, _ := v.(float64)
+	return int(f)
+}
+
+// pandocAttrs returns id, classes and key-value pairs of pandoc Attr.
+func pandocAttrs(v interface{}) (string, []string, [][]string) {
+	attr := pandocList(v)
+	if len(attr) < 3 {
+		return "", nil, nil
+	}
+	classes := []string{}
+	for _, c := range pandocList(attr[1]) {
+		classes = append(classes, pandocString(c))
+	}
+	kv := [][]string{}
+	for _, p := range pandocList(attr[2]) {
+		pair := pandocList(p)
+		if len(pair) == 2 {
+			kv = append(kv, []string{pandocString(pair[0]), pandocString(pair[1])})
+		}
+	}
+	return pandocString(attr[0]), classes, kv
+}
+
+func (im *pandocImporter) fail(what string) {
+	im.unsupported[what] = true
+}
+
+// stringify returns plain text of inlines.
+func (im *pandocImporter) stringify(inlines []interface{}) string {
+	nodes := im.inlines(inlines)
+	var text strings.Builder
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, n := range nodes {
+			text.WriteString(n.Literal)
+			walk(n.Children)
+		}
+	}
+	walk(nodes)
+	return text.String()
+}
+
+func (im *pandocImporter) blocks(blocks []interface{}) []Node {
+	nodes := []Node{}
+	for _, b := range blocks {
+		nodes = append(nodes, im.block(b)...)
+	}
+	return nodes
+}
+
+func (im *pandocImporter) block(b interface{}) []Node {
+	t, c := pandocValue(b)
+	switch t {
+	case "Para", "Plain":
+		return []Node{{Type: Paragraph, Children: im.inlines(pandocList(c))}}
+	case "Header":
+		l := pandocList(c)
+		id, _, _ := pandocAttrs(l[1])
+		n := Node{Type: Heading, Literal: im.stringify(pandocList(l[2])), Attributes: AttributeMap{"level": fmt.Sprintf("%d", pandocInt(l[0]))}}
+		// ids derived from the title are made again by writers
+		if id != "" && id != htmlId(n.Literal) {
+			n.Attributes["id"] = id
+		}
+		return []Node{n}
+	case "BulletList":
+		return []Node{im.list(pandocList(c), AttributeMap{})}
+	case "OrderedList":
+		l := pandocList(c)
+		attrs := AttributeMap{"ordered": "true"}
+		start := pandocInt(pandocList(l[0])[0])
+		if start != 1 {
+			attrs["start"] = fmt.Sprintf("%d", start)
+		}
+		return []Node{im.list(pandocList(l[1]), attrs)}
+	case "CodeBlock":
+		l := pandocList(c)
+		_, classes, kv := pandocAttrs(l[0])
+		n := Node{Type: CodeFence, Literal: pandocString(l[1]) + "\n"}
+		if len(classes) > 0 || len(kv) > 0 {
+			n.Attributes = AttributeMap{}
+		}
+		if len(classes) > 0 {
+			n.Attributes["lang"] = classes[0]
+		}
+		for _, p := range kv {
+			n.Attributes[p[0]] = p[1]
+		}
+		return []Node{n}
+	case "BlockQuote":
+		return []Node{{Type: Blockquote, Children: im.blocks(pandocList(c))}}
+	case "HorizontalRule":
+		return []Node{{Type: ThematicBreak}}
+	case "RawBlock":
+		l := pandocList(c)
+		if pandocString(l[0]) != "html" {
+			im.fail("RawBlock " + pandocString(l[0]))
+			return nil
+		}
+		doc := MarkdownParse([]byte(pandocString(l[1]) + "\n"))
+		stripPositions(&doc)
+		return doc.Children
+	case "Div":
+		l := pandocList(c)
+		_, classes, kv := pandocAttrs(l[0])
+		children := im.blocks(pandocList(l[1]))
+		if len(classes) == 2 && classes[0] == "admonition" {
+			n := Node{Type: Admonition, Attributes: AttributeMap{"level": classes[1]}, Children: children}
+			for _, p := range kv {
+				if p[0] == "title" || p[0] == "collapsible" {
+					n.Attributes[p[0]] = p[1]
+				}
+			}
+			return []Node{n}
+		}
+		if len(classes) == 1 && classes[0] == "tabbed-set" {
+			n := Node{Type: Tabs, Children: []Node{}}
+			for _, ch := range children {
+				if ch.Type == Tab {
+					n.Children = append(n.Children, ch)
+				}
+			}
+			return []Node{n}
+		}
+		if len(classes) == 1 && classes[0] == "tabbed" {
+			n := Node{Type: Tab, Attributes: AttributeMap{"title": ""}, Children: children}
+			for _, p := range kv {
+				if p[0] == "title" || p[0] == "selected" {
+					n.Attributes[p[0]] = p[1]
+				}
+			}
+			return []Node{n}
+		}
+		// attributes of other divs have no place in markdown
+		return children
+	case "Table":
+		return []Node{im.table(pandocList(c))}
+	}
+	im.fail(t)
+	return nil
+}
+
+// list makes list items, leading ballot box of the item becomes its
+// checked attribute.
+func (im *pandocImporter) list(items []interface{}, attrs AttributeMap) Node {
+	n := Node{Type: List, Attributes: attrs, Children: []Node{}}
+	for _, item := range items {
+		li := Node{Type: ListItem, Children: im.blocks(pandocList(item))}
+		if len(li.Children) > 0 && li.Children[0].Type == Paragraph && len(li.Children[0].Children) > 0 {
+			first := &li.Children[0]
+			box := first.Children[0]
+			checked := ""
+			if box.Type == Text && strings.HasPrefix(box.Literal, "☒ ") {
+				checked = "true"
+			} else if box.Type == Text && strings.HasPrefix(box.Literal, "☐ ") {
+				checked = "false"
+			}
+			if checked != "" {
+				li.Attributes = AttributeMap{"checked": checked}
+				first.Children[0].Literal = strings.TrimPrefix(strings.TrimPrefix(box.Literal, "☒ "), "☐ ")
+			}
+		}
+		n.Children = append(n.Children, li)
+	}
+	return n
+}
+
+func (im *pandocImporter) table(l []interface{}) Node {
+	aligns := []string{}
+	for _, spec := range pandocList(l[2]) {
+		align, _ := pandocValue(pandocList(spec)[0])
+		aligns = append(aligns, strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(align, "Default"), "Align")))
+	}
+	cellInlines := func(cell interface{}) []Node {
+		blocks := im.blocks(pandocList(pandocList(cell)[4]))
+		nodes := []Node{}
+		for _, b := range blocks {
+			nodes = append(nodes, b.Children...)
+		}
+		return nodes
+	}
+	head := Node{Type: TableHead}
+	for _, row := range pandocList(pandocList(l[3])[1]) {
+		for i, cell := range pandocList(pandocList(row)[1]) {
+			th := Node{Type: TableCell, Children: cellInlines(cell)}
+			if i < len(aligns) && aligns[i] != "" {
+				th.Attributes = AttributeMap{"align": aligns[i]}
+			}
+			head.Children = append(head.Children, th)
+		}
+	}
+	body := Node{Type: TableBody}
+	for _, tb := range pandocList(l[4]) {
+		for _, row := range pandocList(pandocList(tb)[3]) {
+			r := Node{Type: TableRow}
+			for _, cell := range pandocList(pandocList(row)[1]) {
+				r.Children = append(r.Children, Node{Type: TableCell, Children: cellInlines(cell)})
+			}
+			body.Children = append(body.Children, r)
+		}
+	}
+	return Node{Type: Table, Children: []Node{head, body}}
+}
+
+func (im *pandocImporter) inlines(inlines []interface{}) []Node {
+	nodes := []Node{}
+	add := func(n Node) {
+		// Str and Space come as separate elements, marktome keeps text
+		last := len(nodes) - 1
+		if n.Type == Text && last >= 0 && nodes[last].Type == Text {
+			nodes[last].Literal += n.Literal
+			return
+		}
+		nodes = append(nodes, n)
+	}
+	for _, i := range inlines {
+		for _, n := range im.inline(i) {
+			add(n)
+		}
+	}
+	return nodes
+}
+
+// inliner makes emphasis-like node, plain text stays in its literal
+// like the markdown parser does.
+func (im *pandocImporter) inliner(kind Kind, inlines []interface{}) Node {
+	children := im.inlines(inlines)
+	if len(children) == 1 && children[0].Type == Text {
+		return Node{Type: kind, Literal: children[0].Literal}
+	}
+	return Node{Type: kind, Children: children}
+}
+
+func (im *pandocImporter) inline(i interface{}) []Node {
+	t, c := pandocValue(i)
+	switch t {
+	case "Str":
+		return []Node{{Type: Text, Literal: pandocString(c)}}
+	case "Space":
+		return []Node{{Type: Text, Literal: " "}}
+	case "SoftBreak":
+		return []Node{{Type: Text, Literal: "\n"}}
+	case "LineBreak":
+		return []Node{{Type: LineBreak}}
+	case "Emph":
+		return []Node{im.inliner(Emphasis, pandocList(c))}
+	case "Strong":
+		return []Node{im.inliner(Bold, pandocList(c))}
+	case "Strikeout":
+		return []Node{im.inliner(Strikethrough, pandocList(c))}
+	case "Code":
+		return []Node{{Type: Code, Literal: pandocString(pandocList(c)[1])}}
+	case "Quoted":
+		l := pandocList(c)
+		quote, _ := pandocValue(l[0])
+		open, close := "“", "”"
+		if quote == "SingleQuote" {
+			open, close = "‘", "’"
+		}
+		nodes := []Node{{Type: Text, Literal: open}}
+		nodes = append(nodes, im.inlines(pandocList(l[1]))...)
+		return append(nodes, Node{Type: Text, Literal: close})
+	case "Span":
+		// attributes of spans have no place in markdown
+		return im.inlines(pandocList(pandocList(c)[1]))
+	case "Link":
+		l := pandocList(c)
+		_, classes, _ := pandocAttrs(l[0])
+		target := pandocList(l[2])
+		url := pandocString(target[0])
+		n := Node{Type: Link, Literal: im.stringify(pandocList(l[1])), Attributes: AttributeMap{}}
+		setLinkUrl(&n, url)
+		if title := pandocString(target[1]); title != "" {
+			n.Attributes["title"] = title
+		}
+		if len(classes) > 0 && classes[0] == "uri" {
+			n.Literal = ""
+			n.Attributes["autolink"] = "angle"
+		}
+		return []Node{n}
+	case "Image":
+		l := pandocList(c)
+		target := pandocList(l[2])
+		return []Node{{Type: Image, Literal: im.stringify(pandocList(l[1])), Attributes: AttributeMap{"src": pandocString(target[0])}}}
+	case "Note":
+		im.notes = append(im.notes, Node{Type: FootnoteDef, Children: im.blocks(pandocList(c))})
+		id := fmt.Sprintf("%d", len(im.notes))
+		im.notes[len(im.notes)-1].Attributes = AttributeMap{"id": id}
+		return []Node{{Type: FootnoteRef, Attributes: AttributeMap{"id": id}}}
+	case "RawInline":
+		l := pandocList(c)
+		if pandocString(l[0]) != "html" {
+			im.fail("RawInline " + pandocString(l[0]))
+			return nil
+		}
+		doc := MarkdownParse([]byte(pandocString(l[1])))
+		stripPositions(&doc)
+		if len(doc.Children) == 0 {
+			return nil
+		}
+		return doc.Children[0].Children
+	}
+	im.fail(t)
+	return nil
+}
+
+// stripPositions drops positions of nodes parsed from fragments, which
+// don't point to the imported document.
+func stripPositions(n *Node) {
+	n.Position = nil
+	for i := range n.Children {
+		stripPositions(&n.Children[i])
+	}
+}
+
+// PandocParse converts pandoc JSON AST to the document. Constructors
+// that have no kind in marktome are reported in error.
+func PandocParse(source []byte) (Node, error) {
+	ast := map[string]interface{}{}
+	err := json.Unmarshal(source, &ast)
+	if err != nil {
+		return Node{}, err
+	}
+	im := &pandocImporter{unsupported: map[string]bool{}}
+	doc := Node{Type: Document, Children: im.blocks(pandocList(ast["blocks"]))}
+	doc.Children = append(doc.Children, im.notes...)
+	meta, _ := ast["meta"].(map[string]interface{})
+	for k, v := range meta {
+		t, c := pandocValue(v)
+		if doc.Attributes == nil {
+			doc.Attributes = AttributeMap{}
+		}
+		switch t {
+		case "MetaString":
+			doc.Attributes[k] = pandocString(c)
+		case "MetaInlines":
+			doc.Attributes[k] = im.stringify(pandocList(c))
+		default:
+			im.fail("meta " + t)
+		}
+	}
+	if len(im.unsupported) > 0 {
+		names := []string{}
+		for k := range im.unsupported {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return Node{}, errors.New(fmt.Sprintf("Not supported pandoc elements: %s", strings.Join(names, ", ")))
+	}
+	return doc, nil
+}
+
+func Json2Pandoc(input string, output string) error {
+	doc, err := ReadJson(input)
+	if err != nil {
+		return err
+	}
+	ast, err := Pandoc(&doc, input)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, ast, os.ModePerm)
+}
+
+func Pandoc2Json(input string, output string) error {
+	source, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	doc, err := PandocParse(source)
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s: %v", input, err))
+	}
+	return WriteJson(&doc, output)
+}
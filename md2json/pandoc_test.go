@@ -0,0 +1,120 @@
+package md2json_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// normalizeJson makes json comparable regardless of formatting.
+func normalizeJson(t *testing.T, text []byte) string {
+	var v interface{}
+	err := json.Unmarshal(text, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	normal, _ := json.MarshalIndent(v, "", "  ")
+	return string(normal)
+}
+
+func TestPandoc(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/pandoc/*.md")
+	for _, fp := range paths {
+		name := strings.TrimSuffix(fp, ".md")
+		t.Run(name, func(t *testing.T) {
+			input, _ := os.ReadFile(fp)
+			expected, _ := os.ReadFile(name + ".pandoc.json")
+			doc := md2json.MarkdownParse(input)
+			ast, err := md2json.Pandoc(&doc, fp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := normalizeJson(t, ast)
+			if actual != normalizeJson(t, expected) {
+				t.Errorf("Pandoc()\nactual\n%s\nexpected\n%s", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPandocParse(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/pandoc/*.pandoc.json")
+	for _, fp := range paths {
+		name := strings.TrimSuffix(fp, ".pandoc.json")
+		t.Run(name, func(t *testing.T) {
+			input, _ := os.ReadFile(fp)
+			source, _ := os.ReadFile(name + ".md")
+			original := md2json.MarkdownParse(source)
+			if len(original.Attributes) == 0 {
+				// md2json drops empty meta with the json
+				original.Attributes = nil
+			}
+			expected := md2json.WriteDocument(&original)
+			doc, err := md2json.PandocParse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := md2json.WriteDocument(&doc)
+			if !bytes.Equal(text, expected) {
+				t.Errorf("PandocParse()\nactual\n%s\nexpected\n%s", text, expected)
+			}
+		})
+	}
+}
+
+// TestPandocRoundTrip covers kinds pandoc markdown has no syntax for, they
+// are divs and attributes only the importer reads back.
+func TestPandocRoundTrip(t *testing.T) {
+	input := "!!! note \"Read first\"\n    Admonition text.\n\n" +
+		"```go title=\"main.go\"\npackage main\n```\n\n" +
+		"=== \"Linux\"\n\n    Run `marktome`.\n\n===+ \"Windows\"\n\n    Run *marktome.exe*.\n"
+	doc := md2json.MarkdownParse([]byte(input))
+	doc.Attributes = nil
+	expected := md2json.WriteDocument(&doc)
+	ast, err := md2json.Pandoc(&doc, "page.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := md2json.PandocParse(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := md2json.WriteDocument(&parsed)
+	if !bytes.Equal(text, expected) {
+		t.Errorf("PandocParse()\nactual\n%s\nexpected\n%s", text, expected)
+	}
+}
+
+func TestPandocBlockText(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte("<a\n\ntext\n"))
+	ast, err := md2json.Pandoc(&doc, "page.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ast), `{"c":[{"c":"\u003ca","t":"Str"}],"t":"Plain"}`) {
+		t.Errorf("Pandoc() %s, expected text as Plain", ast)
+	}
+}
+
+func TestPandocUnsupported(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte("Text.\n\n<!-- pagebreak -->\n"))
+	doc.Children = append(doc.Children, md2json.Node{Type: "NewPage"})
+	_, err := md2json.Pandoc(&doc, "page.md")
+	if err == nil || !strings.Contains(err.Error(), "block NewPage") {
+		t.Errorf("Pandoc() error %v, expected NewPage to be reported", err)
+	}
+
+	ast := `{"pandoc-api-version":[1,23,1],"meta":{},"blocks":[` +
+		`{"t":"Para","c":[{"t":"Str","c":"E"},{"t":"Math","c":[{"t":"InlineMath"},"mc^2"]}]},` +
+		`{"t":"LineBlock","c":[[{"t":"Str","c":"line"}]]},` +
+		`{"t":"RawBlock","c":["latex","\\newpage"]}]}`
+	_, err = md2json.PandocParse([]byte(ast))
+	expected := "Not supported pandoc elements: LineBlock, Math, RawBlock latex"
+	if err == nil || err.Error() != expected {
+		t.Errorf("PandocParse() error %v, expected %s", err, expected)
+	}
+}
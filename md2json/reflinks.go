@@ -0,0 +1,153 @@
+package md2json
+
+import (
+	"bytes"
+	"strings"
+)
+
+// linkDefinition is [ref]: url "title" line. It lives in the tree only
+// during parsing, resolveReferenceLinks moves it into links using it.
+const linkDefinition Kind = "LinkDefinition"
+
+func referenceLabel(ref string) string {
+	return strings.ToLower(strings.Join(strings.Fields(ref), " "))
+}
+
+// resolveReferenceLinks removes link definitions from the document and sets
+// href and anchor of [text][ref] links, so they work like inline links.
+// Links without definition are turned back to text.
+func resolveReferenceLinks(doc *Node) {
+	defs := map[string]Node{}
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		if n.Children == nil {
+			return
+		}
+		children := make([]Node, 0, len(n.Children))
+		for i := range n.Children {
+			ch := n.Children[i]
+			if ch.Type == linkDefinition {
+				label := referenceLabel(ch.Attributes["ref"])
+				if _, ok := defs[label]; !ok {
+					defs[label] = ch
+				}
+				continue
+			}
+			collect(&ch)
+			children = append(children, ch)
+		}
+		n.Children = children
+	}
+	collect(doc)
+
+	var resolve func(n *Node) bool
+	resolve = func(n *Node) bool {
+		unresolved := false
+		if n.Type == Link && n.Attributes != nil {
+			ref, ok := n.Attributes["ref"]
+			_, hasHref := n.Attributes["href"]
+			_, hasAnchor := n.Attributes["anchor"]
+			if ok && !hasHref && !hasAnchor {
+				def, found := defs[referenceLabel(ref)]
+				if found {
+					setLinkUrl(n, def.Attributes["url"])
+					title, ok := def.Attributes["title"]
+					if ok {
+						n.Attributes["title"] = title
+					}
+				} else {
+					n.Type = Text
+					n.Literal = "[" + n.Literal + "][" + ref + "]"
+					n.Attributes = nil
+					unresolved = true
+				}
+			}
+		}
+		textChanged := false
+		for i := range n.Children {
+			textChanged = resolve(&n.Children[i]) || textChanged
+		}
+		if textChanged {
+			n.Children = mergeText(n.Children)
+		}
+		return unresolved
+	}
+	resolve(doc)
+}
+
+// mergeText joins adjacent text nodes, i.e. unresolved links with text
+// around them.
+func mergeText(children []Node) []Node {
+	if children == nil {
+		return nil
+	}
+	merged := make([]Node, 0, len(children))
+	for _, ch := range children {
+		last := len(merged) - 1
+		if ch.Type == Text && last >= 0 && merged[last].Type == Text && merged[last].Attributes == nil {
+			merged[last].Literal += ch.Literal
+			if merged[last].Position != nil && ch.Position != nil {
+				merged[last].Position = &Position{Start: merged[last].Position.Start, End: ch.Position.End}
+			}
+			continue
+		}
+		merged = append(merged, ch)
+	}
+	return merged
+}
+
+// dropReferenceLinks returns copy of the document where all links are
+// written inline, titles of definitions stay with the links.
+func dropReferenceLinks(n *Node) Node {
+	n1 := *n
+	if n.Type == Link && n.Attributes != nil {
+		_, ok := n.Attributes["ref"]
+		if ok {
+			n1.Attributes = AttributeMap{}
+			for k, v := range n.Attributes {
+				if k != "ref" {
+					n1.Attributes[k] = v
+				}
+			}
+		}
+	}
+	if n.Children != nil {
+		n1.Children = make([]Node, len(n.Children))
+		for i := range n.Children {
+			n1.Children[i] = dropReferenceLinks(&n.Children[i])
+		}
+	}
+	return n1
+}
+
+// writeLinkDefinitions writes [ref]: url lines for every reference link of
+// the document, once per label.
+func writeLinkDefinitions(n *Node) []byte {
+	var text bytes.Buffer
+	written := map[string]bool{}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if n.Type == Link && n.Attributes != nil {
+			ref, ok := n.Attributes["ref"]
+			if ok && !written[referenceLabel(ref)] {
+				written[referenceLabel(ref)] = true
+				text.WriteString("[")
+				text.WriteString(ref)
+				text.WriteString("]: ")
+				text.WriteString(linkUrl(n))
+				title, ok := n.Attributes["title"]
+				if ok {
+					text.WriteString(" \"")
+					text.WriteString(title)
+					text.WriteString("\"")
+				}
+				text.WriteString("\n")
+			}
+		}
+		for i := range n.Children {
+			walk(&n.Children[i])
+		}
+	}
+	walk(n)
+	return text.Bytes()
+}
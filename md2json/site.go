@@ -0,0 +1,231 @@
+package md2json
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SiteOptions tune the static site.
+type SiteOptions struct {
+	// Template is html/template page with fields of sitePage. Default
+	// is defaultSiteTemplate.
+	Template *template.Template
+}
+
+type siteLink struct {
+	Title string
+	Url   string
+}
+
+type siteTocEntry struct {
+	Level int
+	Id    string
+	Title string
+}
+
+// sitePage is what the site template gets
+type sitePage struct {
+	SiteName string
+	Title    string
+	Content  template.HTML
+	// Nav is sidebar with all pages, current one has class active
+	Nav  template.HTML
+	Toc  []siteTocEntry
+	Prev *siteLink
+	Next *siteLink
+	// Root is relative path to the site root, like ../
+	Root string
+	Meta AttributeMap
+}
+
+const defaultSiteTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - {{.SiteName}}</title>
+<style>
+body { margin: 0; font-family: sans-serif; line-height: 1.5; display: flex; }
+nav.sidebar { width: 16rem; padding: 1rem; border-right: 1px solid #ddd; }
+nav.sidebar ul { list-style: none; padding-left: 1rem; }
+nav.sidebar .active { font-weight: bold; }
+main { flex: 1; padding: 1rem 2rem; max-width: 50rem; }
+nav.toc { width: 14rem; padding: 1rem; font-size: 0.9rem; }
+pre { background: #f5f5f5; padding: 0.5rem; overflow-x: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.25rem 0.5rem; }
+.admonition { border-left: 4px solid #448aff; padding: 0 1rem; margin: 1rem 0; }
+.admonition-title { font-weight: bold; }
+</style>
+</head>
+<body>
+<nav class="sidebar">
+<a href="{{.Root}}index.html">{{.SiteName}}</a>
+{{.Nav}}</nav>
+<main>
+{{.Content}}<footer>
+{{with .Prev}}<a class="prev" href="{{.Url}}">&larr; {{.Title}}</a>
+{{end}}{{with .Next}}<a class="next" href="{{.Url}}">{{.Title}} &rarr;</a>
+{{end}}</footer>
+</main>
+{{if .Toc}}<nav class="toc">
+<ul>
+{{range .Toc}}<li class="toc-{{.Level}}"><a href="#{{.Id}}">{{.Title}}</a></li>
+{{end}}</ul>
+</nav>
+{{end}}</body>
+</html>
+`
+
+// siteUrl is link to target page from the page, both relative to the
+// root of the site.
+func siteUrl(page string, target string) string {
+	rel, err := filepath.Rel(filepath.Dir(page), htmlOutput("", target))
+	if err != nil {
+		return htmlOutput("", target)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// siteRoot is relative path from the page to the root of the site.
+func siteRoot(page string) string {
+	depth := strings.Count(filepath.ToSlash(filepath.Clean(page)), "/")
+	return strings.Repeat("../", depth)
+}
+
+// writeSiteNav writes sidebar for the page as nested lists.
+func writeSiteNav(items []navItem, page string, titles map[string]string) []byte {
+	var text bytes.Buffer
+	text.WriteString("<ul>\n")
+	for _, item := range items {
+		if item.page == "" {
+			text.WriteString(fmt.Sprintf("<li><span>%s</span>\n", html.EscapeString(item.title)))
+			text.Write(writeSiteNav(item.children, page, titles))
+			text.WriteString("</li>\n")
+			continue
+		}
+		class := ""
+		if item.page == page {
+			class = ` class="active"`
+		}
+		text.WriteString(fmt.Sprintf("<li><a%s href=\"%s\">%s</a></li>\n",
+			class, html.EscapeString(siteUrl(page, item.page)), html.EscapeString(titles[item.page])))
+	}
+	text.WriteString("</ul>\n")
+	return text.Bytes()
+}
+
+// siteToc lists second and third level headings of the rendered page.
+func siteToc(doc *Node) []siteTocEntry {
+	toc := []siteTocEntry{}
+	for _, n := range doc.Children {
+		if n.Type != Heading {
+			continue
+		}
+		level, _ := strconv.Atoi(n.Attributes["level"])
+		if level == 2 || level == 3 {
+			toc = append(toc, siteTocEntry{Level: level, Id: n.Attributes["id"], Title: n.Literal})
+		}
+	}
+	return toc
+}
+
+// relinkSitePage makes links to pages of the site, which superlinks
+// resolve relative to the root, relative to the page.
+func relinkSitePage(n *Node, page string, pages map[string]bool) {
+	if n.Type == Link {
+		href, _ := n.Attributes["href"]
+		if pages[filepath.Clean(href)] {
+			attrs := AttributeMap{}
+			for k, v := range n.Attributes {
+				attrs[k] = v
+			}
+			rel, err := filepath.Rel(filepath.Dir(page), href)
+			if err == nil {
+				attrs["href"] = filepath.ToSlash(rel)
+			}
+			n.Attributes = attrs
+		}
+	}
+	for i := range n.Children {
+		relinkSitePage(&n.Children[i], page, pages)
+	}
+}
+
+// BuildSite renders pages of the nav of planarized mkdocs config to html
+// pages with sidebar, table of contents and links to previous and next
+// pages. Images are copied from the directory of the config.
+func BuildSite(mkdocsPath string, outDir string, opts SiteOptions) error {
+	mkdocs, inDir, items, err := readMkdocsNav(mkdocsPath)
+	if err != nil {
+		return err
+	}
+	tmpl := opts.Template
+	if tmpl == nil {
+		tmpl = template.Must(template.New("site").Parse(defaultSiteTemplate))
+	}
+	siteName := ""
+	if name, ok := mkdocs["site_name"]; ok {
+		siteName = fmt.Sprintf("%v", name)
+	}
+
+	pages := navPages(items)
+	docs, titles, err := readNavPages(inDir, pages)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, p := range pages {
+		known[filepath.Clean(p.page)] = true
+	}
+
+	for i, p := range pages {
+		doc := docs[p.page]
+		relinkSitePage(&doc, p.page, known)
+		content, prepared := htmlContent(&doc)
+		page := sitePage{
+			SiteName: siteName,
+			Title:    titles[p.page],
+			Content:  template.HTML(content),
+			Nav:      template.HTML(writeSiteNav(items, p.page, titles)),
+			Toc:      siteToc(&prepared),
+			Root:     siteRoot(p.page),
+			Meta:     doc.Attributes,
+		}
+		if i > 0 {
+			page.Prev = &siteLink{Title: titles[pages[i-1].page], Url: siteUrl(p.page, pages[i-1].page)}
+		}
+		if i < len(pages)-1 {
+			page.Next = &siteLink{Title: titles[pages[i+1].page], Url: siteUrl(p.page, pages[i+1].page)}
+		}
+		var text bytes.Buffer
+		err := tmpl.Execute(&text, page)
+		if err != nil {
+			return err
+		}
+		output := htmlOutput(outDir, p.page)
+		os.MkdirAll(filepath.Dir(output), os.ModePerm)
+		err = os.WriteFile(output, text.Bytes(), os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+
+	// mkdocs sites start with index.md, others lead to the first page
+	if !known["index.md"] && len(pages) > 0 {
+		url := html.EscapeString(siteUrl("index.md", pages[0].page))
+		redirect := fmt.Sprintf("<!DOCTYPE html>\n<meta http-equiv=\"refresh\" content=\"0; url=%s\">\n<a href=\"%s\">%s</a>\n",
+			url, url, html.EscapeString(titles[pages[0].page]))
+		err = os.WriteFile(filepath.Join(outDir, "index.html"), []byte(redirect), os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+	return CopyImages(inDir, filepath.Dir(mkdocsPath), outDir)
+}
@@ -0,0 +1,53 @@
+package md2json_test
+
+import (
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildSite(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), os.ModePerm)
+	for _, fp := range md2json.ListAllMd("testdata/book/docs") {
+		err := md2json.Md2Json(fp, filepath.Join(dir, "docs", filepath.Base(fp)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mkdocs, _ := os.ReadFile("testdata/book/mkdocs.yml")
+	os.WriteFile(filepath.Join(dir, "mkdocs.yml"), mkdocs, os.ModePerm)
+
+	out := filepath.Join(dir, "site")
+	err := md2json.BuildSite(filepath.Join(dir, "mkdocs.yml"), out, md2json.SiteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(out, "install.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<title>Installation - Streaming Server</title>`,
+		`<li><a class="active" href="install.html">Installation</a></li>`,
+		`<li><span>Setup</span>`,
+		`<a class="prev" href="intro.html">&larr; Introduction</a>`,
+		`<a class="next" href="tuning.html">Tuning &rarr;</a>`,
+		`<li class="toc-2"><a href="#install-packages">Packages</a></li>`,
+		`<h2 id="install-packages">Packages</h2>`,
+	} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("install.html has no %s\n%s", expected, page)
+		}
+	}
+	usage, _ := os.ReadFile(filepath.Join(out, "usage.html"))
+	if !strings.Contains(string(usage), `<li><a class="active" href="usage.html">Usage</a></li>`) {
+		t.Errorf("usage.html must use title from nav\n%s", usage)
+	}
+	index, _ := os.ReadFile(filepath.Join(out, "index.html"))
+	if !strings.Contains(string(index), `url=intro.html`) {
+		t.Errorf("index.html must lead to the first page\n%s", index)
+	}
+}
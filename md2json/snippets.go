@@ -18,7 +18,7 @@ func CopySnippets(rootDir string) error {
 				if ok2 {
 					snippets[id] = n.Literal
 				} else {
-					return errors.New(fmt.Sprintf("File %s has snippet without id", path))
+					return errors.New(fmt.Sprintf("File %s has snippet without id", n.Location(path)))
 				}
 			}
 		}
@@ -53,7 +53,7 @@ func CopySnippets(rootDir string) error {
 					n.Literal = text
 					dirty = true
 				} else {
-					return false, errors.New(fmt.Sprintf("failed to find snippet %s for file %s", id, fp))
+					return false, errors.New(fmt.Sprintf("failed to find snippet %s for file %s", id, n.Location(fp)))
 				}
 			} else {
 				// fmt.Printf("Strange HTML %v\n", n)
@@ -77,7 +77,7 @@ func CopySnippets(rootDir string) error {
 		if err != nil {
 			return err
 		}
-		err = loadSnippets(&doc, fp)
+		err = loadSnippets(&doc, doc.Source(fp))
 		if err != nil {
 			return err
 		}
@@ -89,7 +89,7 @@ func CopySnippets(rootDir string) error {
 		if err != nil {
 			return err
 		}
-		dirty, err = replaceSnippets(&doc, fp)
+		dirty, err = replaceSnippets(&doc, doc.Source(fp))
 		if err != nil {
 			return err
 		}
@@ -19,21 +19,25 @@ func calculateRelativeLocation(origin string, target string) string {
 
 func CrosscheckSuperlinks(rootDir string) error {
 	headings := map[string]string{}
+	// where every heading is declared, for diagnostics
+	declared := map[string]string{}
 
 	for _, fp := range ListAllMd(rootDir) {
 		origName := strings.TrimSuffix(strings.TrimPrefix(fp, rootDir), ".md")
 		doc, _ := ReadJson(fp)
+		source := doc.Source(fp)
 		var readHeadings func(n Node) error
 
 		readHeadings = func(n Node) error {
 			if n.Type == Heading && n.Attributes != nil {
 				val, ok := n.Attributes["id"]
 				if ok {
-					old, ok2 := headings[val]
+					_, ok2 := headings[val]
 					if ok2 {
-						return errors.New(fmt.Sprintf("Heading %s double declared in %s and %s\n", val, origName, old))
+						return errors.New(fmt.Sprintf("Heading %s double declared in %s and %s\n", val, n.Location(source), declared[val]))
 					}
 					headings[val] = origName
+					declared[val] = n.Location(source)
 				}
 			}
 			if n.Children != nil {
@@ -56,6 +60,7 @@ func CrosscheckSuperlinks(rootDir string) error {
 		dirty := false
 		doc, _ := ReadJson(fp)
 		origName := strings.TrimSuffix(strings.TrimPrefix(fp, rootDir), ".md")
+		source := doc.Source(fp)
 
 		var checkAnchors func(n *Node) error
 		checkAnchors = func(n *Node) error {
@@ -75,7 +80,7 @@ func CrosscheckSuperlinks(rootDir string) error {
 								n.Attributes["href"] = rel
 							}
 						} else {
-							return errors.New(fmt.Sprintf("Anchor %s in file %s not found in project", anchor, fp))
+							return errors.New(fmt.Sprintf("Anchor %s in file %s not found in project", anchor, n.Location(source)))
 						}
 					}
 				}
@@ -0,0 +1,98 @@
+package md2json
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSvgConverter turns {in} svg into {out} pdf.
+const DefaultSvgConverter = "rsvg-convert -f pdf -o {out} {in}"
+
+// convertSvgImages makes pdf copies of svg images of the document, so
+// pdflatex can include them. Conversion is cached by content of svg.
+// The copy is remembered in pdf attribute of the image.
+func convertSvgImages(n *Node, source string, imageDir string, cacheDir string, converter string) (bool, error) {
+	dirty := false
+	if n.Type == Image {
+		src, _ := n.Attributes["src"]
+		if strings.HasSuffix(strings.ToLower(src), ".svg") && !strings.Contains(src, "://") {
+			svg, err := os.ReadFile(filepath.Join(imageDir, src))
+			if err != nil {
+				return false, errors.New(fmt.Sprintf("File %s has invalid link to image %s", n.Location(source), src))
+			}
+			hash := md5.Sum(svg)
+			cachePath := filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".pdf")
+			if _, err := os.Stat(cachePath); err != nil {
+				svgPath := filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".svg")
+				err = os.WriteFile(svgPath, svg, os.ModePerm)
+				if err != nil {
+					return false, err
+				}
+				command := strings.ReplaceAll(converter, "{in}", svgPath)
+				command = strings.ReplaceAll(command, "{out}", cachePath)
+				cmd := exec.Command("/bin/bash", "-c", command)
+				_, err := cmd.Output()
+				if err != nil {
+					return false, errors.New(fmt.Sprintf("Failed to convert image %s in %s: %v", src, n.Location(source), err))
+				}
+				if _, err := os.Stat(cachePath); err != nil {
+					return false, errors.New(fmt.Sprintf("Converter made no pdf from image %s in %s", src, n.Location(source)))
+				}
+			} else {
+				fmt.Printf("Skip converting %s\n", src)
+			}
+			pdf, err := os.ReadFile(cachePath)
+			if err != nil {
+				return false, err
+			}
+			pdfSrc := strings.TrimSuffix(src, filepath.Ext(src)) + ".pdf"
+			fullPdfPath := filepath.Join(imageDir, pdfSrc)
+			os.MkdirAll(filepath.Dir(fullPdfPath), os.ModePerm)
+			err = os.WriteFile(fullPdfPath, pdf, os.ModePerm)
+			if err != nil {
+				return false, err
+			}
+			if n.Attributes["pdf"] != pdfSrc {
+				n.Attributes["pdf"] = pdfSrc
+				dirty = true
+			}
+		}
+	}
+	for i := range n.Children {
+		d, err := convertSvgImages(&n.Children[i], source, imageDir, cacheDir, converter)
+		if err != nil {
+			return false, err
+		}
+		dirty = d || dirty
+	}
+	return dirty, nil
+}
+
+// Svg2Pdf converts svg images of documents in rootDir with converter
+// command, where {in} and {out} are replaced with svg and pdf paths.
+func Svg2Pdf(rootDir string, imageDir string, cacheDir string, converter string) error {
+	os.MkdirAll(cacheDir, os.ModePerm)
+	for _, fp := range ListAllMd(rootDir) {
+		doc, err := ReadJson(fp)
+		if err != nil {
+			return err
+		}
+		dirty, err := convertSvgImages(&doc, doc.Source(fp), imageDir, cacheDir, converter)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			err = WriteJson(&doc, fp)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package md2json_test
+
+import (
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSvg2Pdf(t *testing.T) {
+	dir := t.TempDir()
+	docDir := filepath.Join(dir, "doc")
+	imageDir := filepath.Join(dir, "img")
+	cacheDir := filepath.Join(dir, "cache")
+	os.MkdirAll(docDir, os.ModePerm)
+	os.MkdirAll(filepath.Join(imageDir, "img"), os.ModePerm)
+	os.WriteFile(filepath.Join(imageDir, "img", "scheme.svg"), []byte("<svg/>"), os.ModePerm)
+	doc := md2json.MarkdownParse([]byte("![Scheme](img/scheme.svg)\n"))
+	md2json.WriteJson(&doc, filepath.Join(docDir, "page.md"))
+
+	err := md2json.Svg2Pdf(docDir, imageDir, cacheDir, "cp {in} {out}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf, err := os.ReadFile(filepath.Join(imageDir, "img", "scheme.pdf"))
+	if err != nil || string(pdf) != "<svg/>" {
+		t.Errorf("Svg2Pdf() made %q, %v", pdf, err)
+	}
+	doc, _ = md2json.ReadJson(filepath.Join(docDir, "page.md"))
+	tex, _ := md2json.Latex(&doc)
+	expected := "\\documentImage{Scheme}{img/scheme.pdf}\n"
+	if !strings.Contains(string(tex), expected) {
+		t.Errorf("Latex()\nactual\n%s\nexpected\n%s", tex, expected)
+	}
+
+	err = md2json.Svg2Pdf(docDir, imageDir, cacheDir, "false")
+	if err != nil {
+		t.Errorf("Svg2Pdf() must use cache, got %v", err)
+	}
+	os.WriteFile(filepath.Join(imageDir, "img", "scheme.svg"), []byte("<svg></svg>"), os.ModePerm)
+	err = md2json.Svg2Pdf(docDir, imageDir, cacheDir, "false")
+	if err == nil || !strings.Contains(err.Error(), "page.md:1:1") {
+		t.Errorf("Svg2Pdf() = %v, expected failed conversion", err)
+	}
+}
+
+func TestLatexSvgPackage(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte("![Scheme](img/scheme.svg)\n"))
+	tex, err := md2json.LatexOptions(&doc, md2json.TexOptions{Svg: "svg"})
+	if err != nil {
+		t.Error(err)
+	}
+	expected := "\\includesvg[width=\\linewidth]{img/scheme}\n"
+	if !strings.Contains(string(tex), expected) {
+		t.Errorf("Latex()\nactual\n%s\nexpected\n%s", tex, expected)
+	}
+}
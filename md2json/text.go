@@ -0,0 +1,408 @@
+package md2json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultTextWidth is width of plain text when options don't set it
+const DefaultTextWidth = 80
+
+// MinTextWidth is the narrowest text, blocks nested in boxes and lists
+// keep at least this width and make their parents wider instead.
+const MinTextWidth = 10
+
+// TextOptions tune the plain text produced from the document.
+type TextOptions struct {
+	// Width is the maximal length of lines, code and tables can be wider.
+	Width int
+	// Path of the document for errors
+	Path string
+}
+
+// textWriter renders nodes as plain text. Links and footnotes become
+// numbered notes, which are written after the text.
+type textWriter struct {
+	notes []string
+	// urls are numbers of notes of links, so the same url gets one note
+	urls map[string]int
+	// footnotes are definitions of footnotes by their id
+	footnotes map[string]*Node
+	// source of the document and nodes it has no text for
+	source      string
+	unsupported []string
+}
+
+// textWidth is the number of characters in the line.
+func textWidth(line string) int {
+	return utf8.RuneCountInString(line)
+}
+
+// wrapText fills lines of the width with words of the text, "\n" forces
+// line break. Lines after the first start with indent, the first one is
+// expected to be prefixed by the caller.
+func wrapText(text string, width int, indent string) []byte {
+	var out bytes.Buffer
+	for i, hard := range strings.Split(text, "\n") {
+		if i > 0 {
+			out.WriteString("\n" + indent)
+		}
+		col := textWidth(indent)
+		start := true
+		for _, word := range strings.Fields(hard) {
+			if !start && col+1+textWidth(word) > width {
+				out.WriteString("\n" + indent)
+				col = textWidth(indent)
+				start = true
+			}
+			if !start {
+				out.WriteByte(' ')
+				col++
+			}
+			out.WriteString(word)
+			col += textWidth(word)
+			start = false
+		}
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
+
+// indentText prefixes every non-empty line of the text.
+func indentText(text []byte, prefix string) []byte {
+	var out bytes.Buffer
+	for _, line := range strings.SplitAfter(string(text), "\n") {
+		if strings.TrimSpace(line) != "" {
+			out.WriteString(prefix)
+		} else if strings.TrimSpace(prefix) != "" && line != "" {
+			out.WriteString(strings.TrimRight(prefix, " "))
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
+
+// PlainText renders the document as wrapped plain text. Kinds of nodes
+// the writer does not know are reported by the error.
+func PlainText(n *Node, opts TextOptions) ([]byte, error) {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultTextWidth
+	}
+	width = max(width, MinTextWidth)
+	doc, footnotes := splitFootnotes(n)
+	w := &textWriter{urls: map[string]int{}, footnotes: map[string]*Node{}, source: n.Source(opts.Path)}
+	for i := range footnotes {
+		w.footnotes[footnotes[i].Attributes["id"]] = &footnotes[i]
+	}
+	var text bytes.Buffer
+	text.Write(w.blocks(&doc, width))
+	if len(w.notes) > 0 {
+		text.WriteString("\n")
+		for i, note := range w.notes {
+			marker := fmt.Sprintf("[%d] ", i+1)
+			text.WriteString(marker)
+			text.Write(wrapText(note, width, strings.Repeat(" ", textWidth(marker))))
+		}
+	}
+	if len(w.unsupported) > 0 {
+		return nil, errors.New(fmt.Sprintf("Not supported in plain text: %s", strings.Join(w.unsupported, ", ")))
+	}
+	return text.Bytes(), nil
+}
+
+// note adds numbered note and returns its marker.
+func (w *textWriter) note(text string) string {
+	w.notes = append(w.notes, text)
+	return fmt.Sprintf("[%d]", len(w.notes))
+}
+
+// blocks writes block children separated by empty lines.
+func (w *textWriter) blocks(n *Node, width int) []byte {
+	var text bytes.Buffer
+	for _, ch := range n.Children {
+		block := w.node(&ch, width)
+		if len(block) == 0 {
+			continue
+		}
+		if text.Len() > 0 {
+			text.WriteByte('\n')
+		}
+		text.Write(block)
+	}
+	return text.Bytes()
+}
+
+// inlines returns text of inline children, "\n" marks hard line breaks.
+func (w *textWriter) inlines(n *Node) string {
+	var text strings.Builder
+	for _, ch := range n.Children {
+		text.Write(w.node(&ch, 0))
+	}
+	return text.String()
+}
+
+// inliner returns content of emphasis-like node, which is either plain
+// literal or nested inline nodes.
+func (w *textWriter) inliner(n *Node) []byte {
+	if n.Children == nil {
+		return []byte(textSoftBreaks(n.Literal))
+	}
+	return []byte(w.inlines(n))
+}
+
+// textSoftBreaks joins lines of the paragraph source, wrapping makes
+// its own lines.
+func textSoftBreaks(text string) string {
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// node writes the node, blocks are wrapped to the width. It covers the
+// kinds writeNode does.
+func (w *textWriter) node(n *Node, width int) []byte {
+	switch n.Type {
+	case Paragraph:
+		return wrapText(w.inlines(n), width, "")
+	case Text:
+		return []byte(textSoftBreaks(n.Literal))
+	case Comment:
+		return []byte{}
+	case Image:
+		return []byte("[image: " + n.Literal + "]")
+	case Link:
+		if _, ok := n.Attributes["autolink"]; ok {
+			return []byte(n.Attributes["href"])
+		}
+		text := w.inliner(n)
+		url := linkUrl(n)
+		// links inside the documentation lead nowhere in plain text
+		if !strings.Contains(url, "://") && !strings.HasPrefix(url, "mailto:") {
+			return text
+		}
+		number, ok := w.urls[url]
+		if !ok {
+			w.note(url)
+			number = len(w.notes)
+			w.urls[url] = number
+		}
+		return append(text, []byte(fmt.Sprintf(" [%d]", number))...)
+	case Emphasis, Bold, Strikethrough:
+		return w.inliner(n)
+	case Code:
+		return []byte(n.Literal)
+	case Heading:
+		return w.heading(n)
+	case List:
+		return w.list(n, width)
+	case Admonition:
+		return w.admonition(n, width)
+	case Tabs:
+		return w.tabs(n, width)
+	case CodeFence:
+		return indentText([]byte(n.Literal), "    ")
+	case HTML:
+		return []byte{}
+	case Table:
+		return w.table(n)
+	case Blockquote:
+		return indentText(w.blocks(n, textInner(width, 2)), "> ")
+	case FootnoteRef:
+		def, ok := w.footnotes[n.Attributes["id"]]
+		if !ok {
+			return []byte("[" + n.Attributes["id"] + "]")
+		}
+		var content []string
+		for _, ch := range def.Children {
+			content = append(content, w.inlines(&ch))
+		}
+		return []byte(w.note(strings.Join(content, "\n")))
+	case FootnoteDef:
+		// definitions are written with notes
+		return []byte{}
+	case ThematicBreak:
+		return []byte(strings.Repeat("-", max(width, 0)) + "\n")
+	case LineBreak:
+		return []byte("\n")
+	case "NewPage":
+		return []byte{}
+	default:
+		w.unsupported = append(w.unsupported, fmt.Sprintf("%s at %s", n.Type, n.Location(w.source)))
+	}
+	return []byte{}
+}
+
+// heading underlines titles of the first two levels.
+func (w *textWriter) heading(n *Node) []byte {
+	title := n.Literal
+	switch n.Attributes["level"] {
+	case "1":
+		return []byte(title + "\n" + strings.Repeat("=", textWidth(title)) + "\n")
+	case "2":
+		return []byte(title + "\n" + strings.Repeat("-", textWidth(title)) + "\n")
+	}
+	return []byte(title + "\n")
+}
+
+// list writes items with hanging indent: lines after the marker start
+// under the text of the first line.
+func (w *textWriter) list(n *Node, width int) []byte {
+	var text bytes.Buffer
+	_, ordered := n.Attributes["ordered"]
+	number := 1
+	if start, ok := n.Attributes["start"]; ok {
+		fmt.Sscan(start, &number)
+	}
+	// numbers are aligned, so text of all items starts in one column
+	markerWidth := 2
+	if ordered {
+		markerWidth = len(fmt.Sprintf("%d. ", number+len(n.Children)-1))
+	}
+	for i, item := range n.Children {
+		marker := "* "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", number+i)
+		}
+		marker += strings.Repeat(" ", markerWidth-len(marker))
+		switch item.Attributes["checked"] {
+		case "true":
+			marker += "[x] "
+		case "false":
+			marker += "[ ] "
+		}
+		indent := strings.Repeat(" ", textWidth(marker))
+		body := indentText(w.blocks(&item, textInner(width, textWidth(marker))), indent)
+		text.WriteString(marker)
+		text.Write(bytes.TrimPrefix(body, []byte(indent)))
+	}
+	return text.Bytes()
+}
+
+// admonition writes box around the blocks, titled by the label. The box
+// grows to fit the label and lines wider than the width, like code.
+func (w *textWriter) admonition(n *Node, width int) []byte {
+	var text bytes.Buffer
+	label := n.AdmonitionTitle()
+	inner := textInner(width, 4)
+	top := "+--"
+	if label != "" {
+		top += " " + label + " "
+	}
+	inner = max(inner, textWidth(top)-3)
+	lines := strings.Split(strings.TrimSuffix(string(w.blocks(n, inner)), "\n"), "\n")
+	for _, line := range lines {
+		inner = max(inner, textWidth(line))
+	}
+	text.WriteString(top + strings.Repeat("-", max(inner+3-textWidth(top), 0)) + "+\n")
+	for _, line := range lines {
+		text.WriteString("| " + line + strings.Repeat(" ", max(inner-textWidth(line), 0)) + " |\n")
+	}
+	text.WriteString("+" + strings.Repeat("-", inner+2) + "+\n")
+	return text.Bytes()
+}
+
+// tabs writes every tab as its title followed by indented blocks.
+func (w *textWriter) tabs(n *Node, width int) []byte {
+	var text bytes.Buffer
+	for i, tab := range n.Children {
+		if i > 0 {
+			text.WriteByte('\n')
+		}
+		text.WriteString(tab.Attributes["title"] + ":\n")
+		text.Write(indentText(w.blocks(&tab, textInner(width, 4)), "    "))
+	}
+	return text.Bytes()
+}
+
+// textInner is width of blocks nested in the block of the width, which
+// takes used columns for its markers.
+func textInner(width int, used int) int {
+	return max(width-used, MinTextWidth)
+}
+
+// textCell pads the cell to the width by its alignment.
+func textCell(cell string, width int, align string) string {
+	pad := width - textWidth(cell)
+	switch align {
+	case "right":
+		return strings.Repeat(" ", pad) + cell
+	case "center":
+		return strings.Repeat(" ", pad/2) + cell + strings.Repeat(" ", pad-pad/2)
+	}
+	return cell + strings.Repeat(" ", pad)
+}
+
+// table writes ASCII grid with columns as wide as their widest cell.
+func (w *textWriter) table(n *Node) []byte {
+	header := n.Children[0]
+	body := n.Children[1]
+	rows := [][]string{{}}
+	aligns := []string{}
+	for _, th := range header.Children {
+		aligns = append(aligns, th.Attributes["align"])
+		if th.Type == Text {
+			rows[0] = append(rows[0], th.Literal)
+		} else {
+			rows[0] = append(rows[0], w.inlines(&th))
+		}
+	}
+	for _, row := range body.Children {
+		cells := []string{}
+		for _, cell := range row.Children {
+			cells = append(cells, strings.ReplaceAll(w.inlines(&cell), "\n", " "))
+		}
+		rows = append(rows, cells)
+	}
+	widths := make([]int, len(aligns))
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) && textWidth(cell) > widths[i] {
+				widths[i] = textWidth(cell)
+			}
+		}
+	}
+	rule := func(c string) string {
+		line := "+"
+		for _, width := range widths {
+			line += strings.Repeat(c, width+2) + "+"
+		}
+		return line + "\n"
+	}
+	var text bytes.Buffer
+	text.WriteString(rule("-"))
+	for r, row := range rows {
+		text.WriteString("|")
+		for i, width := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			text.WriteString(" " + textCell(cell, width, aligns[i]) + " |")
+		}
+		text.WriteString("\n")
+		if r == 0 {
+			text.WriteString(rule("="))
+		}
+	}
+	text.WriteString(rule("-"))
+	return text.Bytes()
+}
+
+// Json2Text writes document as plain text.
+func Json2Text(input string, output string, opts TextOptions) error {
+	doc, err := ReadJson(input)
+	if err != nil {
+		return err
+	}
+	if opts.Path == "" {
+		opts.Path = input
+	}
+	text, err := PlainText(&doc, opts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, text, os.ModePerm)
+}
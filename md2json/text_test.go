@@ -0,0 +1,162 @@
+package md2json_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"marktome/md2json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	paths, _ := filepath.Glob("testdata/text/*.md")
+	for _, fp := range paths {
+		name := strings.TrimSuffix(fp, ".md")
+		t.Run(name, func(t *testing.T) {
+			input, _ := os.ReadFile(fp)
+			expected, _ := os.ReadFile(name + ".txt")
+			doc := md2json.MarkdownParse(input)
+			text, err := md2json.PlainText(&doc, md2json.TextOptions{Width: 60})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(text, expected) {
+				t.Errorf("PlainText()\nactual\n%s\nexpected\n%s", text, expected)
+			}
+		})
+	}
+}
+
+func TestPlainTextWidth(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte("one two three four five six\n"))
+	text, _ := md2json.PlainText(&doc, md2json.TextOptions{Width: 10})
+	expected := "one two\nthree four\nfive six\n"
+	if string(text) != expected {
+		t.Errorf("PlainText()\nactual\n%s\nexpected\n%s", text, expected)
+	}
+}
+
+func TestPlainTextNarrow(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte("!!! note\n    Outer text.\n\n    !!! tip\n        Inner text with words.\n\n    ---\n"))
+	// nested boxes keep the minimal width and the outer box grows
+	expected := "+-- Note --------+\n" +
+		"| Outer          |\n" +
+		"| text.          |\n" +
+		"|                |\n" +
+		"| +-- Tip -----+ |\n" +
+		"| | Inner text | |\n" +
+		"| | with       | |\n" +
+		"| | words.     | |\n" +
+		"| +------------+ |\n" +
+		"|                |\n" +
+		"| ----------     |\n" +
+		"+----------------+\n"
+	for _, width := range []int{3, 8, 14} {
+		text, err := md2json.PlainText(&doc, md2json.TextOptions{Width: width})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != expected {
+			t.Errorf("PlainText(width %d)\nactual\n%s\nexpected\n%s", width, text, expected)
+		}
+	}
+
+	err := md2json.Command_json2text([]string{"in.json", "out.txt", "width", "3"})
+	if err == nil || !strings.Contains(err.Error(), "width 3") {
+		t.Errorf("json2text width 3 = %v, expected error", err)
+	}
+}
+
+// everyKind has every kind of node, so writers are checked to know them all.
+const everyKind = "# Title\n\n" +
+	"Text *emph*, **bold**, ~~old~~, `code`, [link](https://example.com), ![Image](a.png), <b>tag</b>, note[^1] and break  \n" +
+	"here <!-- comment -->.\n\n" +
+	"* item\n\n" +
+	"> quote\n\n" +
+	"!!! note \"Title\"\n    Admonition.\n\n" +
+	"```go\nfmt.Println()\n```\n\n" +
+	"| a | b |\n|---|---|\n| 1 | 2 |\n\n" +
+	"---\n\n" +
+	"=== \"Tab\"\n\n    Content.\n\n" +
+	"[^1]: Footnote.\n"
+
+// documentKinds returns kinds declared in document.go.
+func documentKinds(t *testing.T) []md2json.Kind {
+	f, err := parser.ParseFile(token.NewFileSet(), "document.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := []md2json.Kind{}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); !ok || ident.Name != "Kind" {
+				continue
+			}
+			for _, v := range value.Values {
+				kinds = append(kinds, md2json.Kind(strings.Trim(v.(*ast.BasicLit).Value, `"`)))
+			}
+		}
+	}
+	return kinds
+}
+
+// captureStdout returns what f prints, writers print unknown kinds.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	f()
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestEveryKind(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte(everyKind))
+	found := map[md2json.Kind]bool{}
+	var walk func(n *md2json.Node)
+	walk = func(n *md2json.Node) {
+		found[n.Type] = true
+		for i := range n.Children {
+			walk(&n.Children[i])
+		}
+	}
+	walk(&doc)
+	for _, kind := range documentKinds(t) {
+		if !found[kind] {
+			t.Errorf("%s is missing in the document, add it to everyKind", kind)
+		}
+	}
+
+	out := captureStdout(t, func() { md2json.WriteDocument(&doc) })
+	if out != "" {
+		t.Errorf("WriteDocument() does not know\n%s", out)
+	}
+	_, err := md2json.PlainText(&doc, md2json.TextOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPlainTextUnknown(t *testing.T) {
+	doc := md2json.MarkdownParse([]byte("Text.\n"))
+	doc.Children = append(doc.Children, md2json.Node{Type: "Unknown"})
+	_, err := md2json.PlainText(&doc, md2json.TextOptions{Path: "page.md"})
+	if err == nil || err.Error() != "Not supported in plain text: Unknown at page.md" {
+		t.Errorf("PlainText() error %v, expected Unknown to be reported", err)
+	}
+}